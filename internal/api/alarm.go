@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/gorilla/mux"
+)
+
+//hasRuleAccess check that the user is allowed on the rule group, rules without group are reserved to the administrators
+func hasRuleAccess(auth Auth, rule database.AlarmRule) bool {
+	if rule.Group == nil {
+		return auth.Privilege == PrivilegeAdmin
+	}
+	return auth.HasGroupAccess(*rule.Group)
+}
+
+func (api *API) getV1Alarms(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	alarms := []database.Alarm{}
+	for _, alarm := range api.core.GetAlarms() {
+		if alarm.Group == nil || auth.HasGroupAccess(*alarm.Group) {
+			alarms = append(alarms, alarm)
+		}
+	}
+	api.writeJSON(w, alarms)
+}
+
+func (api *API) getV1AlarmRules(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	rules := make(map[string]database.AlarmRule)
+	for name, rule := range database.GetAlarmRules(api.db) {
+		if rule.Group == nil || auth.HasGroupAccess(*rule.Group) {
+			rules[name] = rule
+		}
+	}
+	api.writeJSON(w, rules)
+}
+
+func (api *API) setV1AlarmRule(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Error reading request body")
+		return
+	}
+	var rule database.AlarmRule
+	err = json.Unmarshal(body, &rule)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+		return
+	}
+	rule.Mac = strings.ToUpper(rule.Mac)
+	err = rule.Check()
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	auth := getAuth(req)
+	if !hasRuleAccess(auth, rule) {
+		api.sendError(w, http.StatusForbidden, "Alarm rule "+rule.Name+" not allowed")
+		return
+	}
+	old := database.GetAlarmRule(api.db, rule.Name)
+	if old != nil && !hasRuleAccess(auth, *old) {
+		api.sendError(w, http.StatusForbidden, "Alarm rule "+rule.Name+" not allowed")
+		return
+	}
+	err = database.SaveAlarmRule(api.db, rule)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) removeV1AlarmRule(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	name := mux.Vars(req)["name"]
+	rule := database.GetAlarmRule(api.db, name)
+	if rule == nil {
+		api.sendError(w, http.StatusNotFound, "Alarm rule "+name+" not found")
+		return
+	}
+	if !hasRuleAccess(getAuth(req), *rule) {
+		api.sendError(w, http.StatusForbidden, "Alarm rule "+name+" not allowed")
+		return
+	}
+	err := database.RemoveAlarmRule(api.db, name)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
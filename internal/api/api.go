@@ -4,22 +4,70 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/energieip/common-components-go/pkg/dblind"
+	"github.com/energieip/common-components-go/pkg/dhvac"
+	dl "github.com/energieip/common-components-go/pkg/dled"
+	dn "github.com/energieip/common-components-go/pkg/dnanosense"
+	ds "github.com/energieip/common-components-go/pkg/dsensor"
 	"github.com/energieip/common-components-go/pkg/dswitch"
+	"github.com/energieip/common-components-go/pkg/dwago"
 	pkg "github.com/energieip/common-components-go/pkg/service"
 	"github.com/energieip/swh200-firmware-go/internal/database"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/romana/rlog"
 )
 
 type API struct {
 	db             database.Database
-	certificate    string
-	keyfile        string
 	apiIP          string
-	apiPort        string
 	apiPassword    string
 	browsingFolder string
 	consumption    *dswitch.SwitchConsumptions
+	core           CoreService
+	tokens         tokenStore
+	upgrader       websocket.Upgrader
+	wsClients      wsClients
+	eventsAPI      chan Event
+	server         HTTPServer
+}
+
+//HTTPServer serve the API requests, blocking until the server stops
+type HTTPServer interface {
+	ListenAndServe(handler http.Handler) error
+}
+
+//tlsServer HTTPS server on the configured API address
+type tlsServer struct {
+	addr        string
+	certificate string
+	keyfile     string
+}
+
+func (srv tlsServer) ListenAndServe(handler http.Handler) error {
+	return http.ListenAndServeTLS(srv.addr, srv.certificate, srv.keyfile, handler)
+}
+
+//CoreService runtime information provided by the switch core service
+type CoreService interface {
+	GetLeds() map[string]dl.Led
+	GetSensors() map[string]ds.Sensor
+	GetBlinds() map[string]dblind.Blind
+	GetHvacs() map[string]dhvac.Hvac
+	GetWagos() map[string]dwago.Wago
+	GetNanos() map[string]dn.Nanosense
+	GetGroupsStatus() map[int]database.GroupStatus
+	SendGroupCommand(grID int, payload []byte) error
+	RecallGroupScene(grID int, scene string) error
+	StartGroupCalibration(grID int, req database.CalibrationRequest) error
+	ReloadGroupSettings(table database.GroupSettingsTable, grID int)
+	ExportConfig() database.ConfigBackup
+	RestoreConfig(backup database.ConfigBackup) error
+	GetEnergy(period string, from, to time.Time) []database.EnergyBucket
+	GetMetrics() CoreMetrics
+	GetAlarms() []database.Alarm
 }
 
 type APIInfo struct {
@@ -30,6 +78,11 @@ type APIFunctions struct {
 	Functions []string `json:"functions"`
 }
 
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
 func (api *API) getAPIs(w http.ResponseWriter, req *http.Request) {
 	api.setDefaultHeader(w)
 	versions := []string{"v1.0"}
@@ -40,19 +93,36 @@ func (api *API) getAPIs(w http.ResponseWriter, req *http.Request) {
 	w.Write(inrec)
 }
 
-//InitAPI start API connection
-func InitAPI(db database.Database, conf pkg.ServiceConfig, conso *dswitch.SwitchConsumptions) *API {
+//InitAPI start API connection, on the configured TLS address when server is nil
+func InitAPI(db database.Database, conf pkg.ServiceConfig, conso *dswitch.SwitchConsumptions, core CoreService, server HTTPServer) *API {
 	api := API{
 		db:             db,
-		certificate:    conf.ExternalAPI.CertPath,
-		keyfile:        conf.ExternalAPI.KeyPath,
 		apiIP:          conf.ExternalAPI.IP,
 		apiPassword:    conf.ExternalAPI.Password,
-		apiPort:        conf.ExternalAPI.Port,
 		browsingFolder: conf.ExternalAPI.BrowsingFolder,
 		consumption:    conso,
+		core:           core,
+		tokens: tokenStore{
+			tokens: make(map[string]token),
+		},
+		wsClients: wsClients{
+			clients: make(map[*wsClient]bool),
+		},
+		eventsAPI: make(chan Event, eventsQueueSize),
+		server:    server,
+	}
+	if api.server == nil {
+		api.server = tlsServer{
+			addr:        conf.ExternalAPI.IP + ":" + conf.ExternalAPI.Port,
+			certificate: conf.ExternalAPI.CertPath,
+			keyfile:     conf.ExternalAPI.KeyPath,
+		}
+	}
+	api.upgrader = websocket.Upgrader{
+		CheckOrigin: api.checkOrigin,
 	}
-	go api.swagger()
+	go api.websocketEvents()
+	go api.serve(api.router())
 	return &api
 }
 
@@ -61,9 +131,23 @@ func (api *API) setDefaultHeader(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 }
 
+func (api *API) writeJSON(w http.ResponseWriter, obj interface{}) {
+	inrec, _ := json.MarshalIndent(obj, "", "  ")
+	w.Write(inrec)
+}
+
+func (api *API) sendError(w http.ResponseWriter, code int, message string) {
+	rlog.Error(message)
+	w.WriteHeader(code)
+	api.writeJSON(w, APIError{
+		Code:    code,
+		Message: message,
+	})
+}
+
 func (api *API) getFunctions(w http.ResponseWriter, req *http.Request) {
 	api.setDefaultHeader(w)
-	functions := []string{"/versions"}
+	functions := []string{"/versions", "/metrics"}
 	apiInfo := APIFunctions{
 		Functions: functions,
 	}
@@ -74,7 +158,27 @@ func (api *API) getFunctions(w http.ResponseWriter, req *http.Request) {
 func (api *API) getV1Functions(w http.ResponseWriter, req *http.Request) {
 	api.setDefaultHeader(w)
 	apiV1 := "/v1.0"
-	functions := []string{apiV1 + "/status/consumptions"}
+	functions := []string{
+		apiV1 + "/status/consumptions",
+		apiV1 + "/status/energy",
+		apiV1 + "/status/leds",
+		apiV1 + "/status/sensors",
+		apiV1 + "/status/blinds",
+		apiV1 + "/status/hvacs",
+		apiV1 + "/status/wagos",
+		apiV1 + "/status/nanos",
+		apiV1 + "/groups",
+		apiV1 + "/schedules",
+		apiV1 + "/events",
+		apiV1 + "/journal",
+		apiV1 + "/alarms",
+		apiV1 + "/alarms/rules",
+		apiV1 + "/user/login",
+		apiV1 + "/user/logout",
+		apiV1 + "/user/info",
+		apiV1 + "/users",
+		apiV1 + "/backup",
+	}
 	apiInfo := APIFunctions{
 		Functions: functions,
 	}
@@ -92,17 +196,79 @@ type APIV1Consumption struct {
 	Power int `json:"power"`
 }
 
-func (api *API) swagger() {
+func (api *API) router() *mux.Router {
 	router := mux.NewRouter()
 	sh := http.StripPrefix("/swaggerui/", http.FileServer(http.Dir("/data/www/swaggerui/")))
 	router.PathPrefix("/swaggerui/").Handler(sh)
 
+	router.HandleFunc("/metrics", api.authorize(PrivilegeReader, api.getMetrics)).Methods("GET")
+
 	// API v1.0
 	apiV1 := "/v1.0"
 	router.HandleFunc(apiV1+"/functions", api.getV1Functions).Methods("GET")
 
 	//status
-	router.HandleFunc(apiV1+"/status/consumptions", api.getV1Consumptions).Methods("GET")
+	router.HandleFunc(apiV1+"/status/consumptions", api.authorize(PrivilegeReader, api.getV1Consumptions)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/energy", api.authorize(PrivilegeReader, api.getV1Energy)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/leds", api.authorize(PrivilegeReader, api.getV1Leds)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/leds/{mac}", api.authorize(PrivilegeReader, api.getV1Led)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/sensors", api.authorize(PrivilegeReader, api.getV1Sensors)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/sensors/{mac}", api.authorize(PrivilegeReader, api.getV1Sensor)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/blinds", api.authorize(PrivilegeReader, api.getV1Blinds)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/blinds/{mac}", api.authorize(PrivilegeReader, api.getV1Blind)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/hvacs", api.authorize(PrivilegeReader, api.getV1Hvacs)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/hvacs/{mac}", api.authorize(PrivilegeReader, api.getV1Hvac)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/wagos", api.authorize(PrivilegeReader, api.getV1Wagos)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/wagos/{mac}", api.authorize(PrivilegeReader, api.getV1Wago)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/nanos", api.authorize(PrivilegeReader, api.getV1Nanos)).Methods("GET")
+	router.HandleFunc(apiV1+"/status/nanos/{mac}", api.authorize(PrivilegeReader, api.getV1Nano)).Methods("GET")
+
+	//groups
+	router.HandleFunc(apiV1+"/groups", api.authorize(PrivilegeReader, api.getV1Groups)).Methods("GET")
+	router.HandleFunc(apiV1+"/groups/{id}", api.authorize(PrivilegeReader, api.getV1Group)).Methods("GET")
+	router.HandleFunc(apiV1+"/groups/{id}/commands", api.authorize(PrivilegeOperator, api.setV1GroupCommand)).Methods("POST")
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/schedule", database.ScheduleSettings)
+
+	router.HandleFunc(apiV1+"/groups/{id}/scenes", api.authorize(PrivilegeReader, api.getV1GroupScenes)).Methods("GET")
+	router.HandleFunc(apiV1+"/groups/{id}/scenes/{scene}", api.authorize(PrivilegeOperator, api.setV1GroupScene)).Methods("POST")
+	router.HandleFunc(apiV1+"/groups/{id}/scenes/{scene}", api.authorize(PrivilegeOperator, api.removeV1GroupScene)).Methods("DELETE")
+	router.HandleFunc(apiV1+"/groups/{id}/scenes/{scene}/recall", api.authorize(PrivilegeOperator, api.recallV1GroupScene)).Methods("POST")
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/buttons", database.ButtonSettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/daylight", database.DaylightSettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/windowInterlock", database.WindowInterlockSettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/hvacOccupancy", database.HvacOccupancySettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/blindAutomation", database.BlindAutomationSettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/blindChannels", database.BlindChannelSettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/thermalControl", database.ThermalControlSettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/airQuality", database.AirQualitySettings)
+	api.handleGroupSettings(router, apiV1+"/groups/{id}/sensorRules", database.SensorRuleSettings)
+	router.HandleFunc(apiV1+"/groups/{id}/calibration", api.authorize(PrivilegeReader, api.getV1GroupCalibration)).Methods("GET")
+	router.HandleFunc(apiV1+"/groups/{id}/calibration", api.authorize(PrivilegeOperator, api.startV1GroupCalibration)).Methods("POST")
+
+	//schedules
+	router.HandleFunc(apiV1+"/schedules", api.authorize(PrivilegeReader, api.getV1Schedules)).Methods("GET")
+
+	//events
+	router.HandleFunc(apiV1+"/events", api.authorizeWebsocket(PrivilegeReader, api.webEvents)).Methods("GET")
+	router.HandleFunc(apiV1+"/journal", api.authorize(PrivilegeReader, api.getV1Journal)).Methods("GET")
+
+	//alarms
+	router.HandleFunc(apiV1+"/alarms", api.authorize(PrivilegeReader, api.getV1Alarms)).Methods("GET")
+	router.HandleFunc(apiV1+"/alarms/rules", api.authorize(PrivilegeReader, api.getV1AlarmRules)).Methods("GET")
+	router.HandleFunc(apiV1+"/alarms/rules", api.authorize(PrivilegeOperator, api.setV1AlarmRule)).Methods("POST")
+	router.HandleFunc(apiV1+"/alarms/rules/{name}", api.authorize(PrivilegeOperator, api.removeV1AlarmRule)).Methods("DELETE")
+
+	//users
+	router.HandleFunc(apiV1+"/user/login", api.userLogin).Methods("POST")
+	router.HandleFunc(apiV1+"/user/logout", api.userLogout).Methods("POST")
+	router.HandleFunc(apiV1+"/user/info", api.authorize(PrivilegeReader, api.getV1UserInfo)).Methods("GET")
+	router.HandleFunc(apiV1+"/users", api.authorize(PrivilegeAdmin, api.getV1Users)).Methods("GET")
+	router.HandleFunc(apiV1+"/users/{hash}/privilege", api.authorize(PrivilegeAdmin, api.setV1UserPrivilege)).Methods("POST")
+	router.HandleFunc(apiV1+"/users/{hash}/credential", api.authorize(PrivilegeAdmin, api.setV1UserCredential)).Methods("POST")
+
+	//backup
+	router.HandleFunc(apiV1+"/backup", api.authorize(PrivilegeAdmin, api.getV1Backup)).Methods("GET")
+	router.HandleFunc(apiV1+"/backup", api.authorize(PrivilegeAdmin, api.restoreV1Backup)).Methods("POST")
 
 	//unversionned API
 	router.HandleFunc("/versions", api.getAPIs).Methods("GET")
@@ -113,5 +279,12 @@ func (api *API) swagger() {
 		router.PathPrefix("/").Handler(sh2)
 	}
 
-	log.Fatal(http.ListenAndServeTLS(api.apiIP+":"+api.apiPort, api.certificate, api.keyfile, router))
+	return router
+}
+
+func (api *API) serve(handler http.Handler) {
+	err := api.server.ListenAndServe(handler)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
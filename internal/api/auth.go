@@ -0,0 +1,427 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	PrivilegeReader   = "reader"   //read status of the allowed groups
+	PrivilegeOperator = "operator" //read and command the allowed groups
+	PrivilegeAdmin    = "admin"    //full access
+
+	AdminLogin = "admin"
+
+	DefaultPrivilege = PrivilegeReader
+	TokenDuration    = time.Hour
+
+	passwordIterations = 10000
+	passwordSaltSize   = 16
+	passwordKeySize    = 32
+)
+
+type contextKey string
+
+const authKey contextKey = "auth"
+
+//Auth authenticated user information
+type Auth struct {
+	Login        string       `json:"login"`
+	UserHash     string       `json:"-"`
+	Privilege    string       `json:"privilege"`
+	AccessGroups map[int]bool `json:"-"`
+}
+
+type token struct {
+	auth    Auth
+	expires time.Time
+}
+
+//tokenStore in memory bearer tokens
+type tokenStore struct {
+	sync.Mutex
+	tokens map[string]token
+}
+
+type APICredentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type APIToken struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int    `json:"expiresIn"` //in seconds
+	Privilege   string `json:"privilege"`
+}
+
+type APIUser struct {
+	UserHash     string `json:"userHash"`
+	AccessGroups []int  `json:"accessGroups"`
+	Privilege    string `json:"privilege"`
+}
+
+type APIUserCredential struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+func passwordKey(password string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(password), salt, iterations, passwordKeySize, sha256.New)
+}
+
+//newUserCredential salt and derive the password of a user login
+func newUserCredential(userHash, login, password string) (database.UserCredential, error) {
+	salt := make([]byte, passwordSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return database.UserCredential{}, err
+	}
+	return database.UserCredential{
+		Login:      login,
+		UserHash:   userHash,
+		Salt:       hex.EncodeToString(salt),
+		Key:        hex.EncodeToString(passwordKey(password, salt, passwordIterations)),
+		Iterations: passwordIterations,
+	}, nil
+}
+
+//checkPassword compare the password with the stored key
+func checkPassword(cred database.UserCredential, password string) bool {
+	salt, err := hex.DecodeString(cred.Salt)
+	if err != nil {
+		return false
+	}
+	key, err := hex.DecodeString(cred.Key)
+	if err != nil || cred.Iterations <= 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare(passwordKey(password, salt, cred.Iterations), key) == 1
+}
+
+func privilegeLevel(privilege string) int {
+	switch privilege {
+	case PrivilegeReader:
+		return 1
+	case PrivilegeOperator:
+		return 2
+	case PrivilegeAdmin:
+		return 3
+	}
+	return 0
+}
+
+//HasPrivilege check that the user has at least the given privilege
+func (auth Auth) HasPrivilege(privilege string) bool {
+	return privilegeLevel(auth.Privilege) >= privilegeLevel(privilege)
+}
+
+//HasGroupAccess check that the user is allowed on the given group
+func (auth Auth) HasGroupAccess(grID int) bool {
+	if auth.Privilege == PrivilegeAdmin {
+		return true
+	}
+	_, ok := auth.AccessGroups[grID]
+	return ok
+}
+
+func (api *API) checkCredentials(login, password string) *Auth {
+	if login == AdminLogin {
+		if api.apiPassword == "" || subtle.ConstantTimeCompare([]byte(password), []byte(api.apiPassword)) != 1 {
+			return nil
+		}
+		return &Auth{
+			Login:     login,
+			Privilege: PrivilegeAdmin,
+		}
+	}
+	cred := database.GetUserCredential(api.db, login)
+	if cred == nil || !checkPassword(*cred, password) {
+		return nil
+	}
+	hash := cred.UserHash
+	user := database.GetUser(api.db, hash)
+	if user == nil {
+		return nil
+	}
+	privilege := DefaultPrivilege
+	userPrivilege := database.GetUserPrivilege(api.db, hash)
+	if userPrivilege != nil && privilegeLevel(userPrivilege.Privilege) > 0 {
+		privilege = userPrivilege.Privilege
+	}
+	groups := make(map[int]bool)
+	for _, gr := range user.AccessGroups {
+		groups[gr] = true
+	}
+	return &Auth{
+		Login:        login,
+		UserHash:     hash,
+		Privilege:    privilege,
+		AccessGroups: groups,
+	}
+}
+
+func (api *API) newToken(auth Auth) string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	value := hex.EncodeToString(buf)
+	api.tokens.Lock()
+	defer api.tokens.Unlock()
+	now := time.Now()
+	for key, tok := range api.tokens.tokens {
+		if now.After(tok.expires) {
+			delete(api.tokens.tokens, key)
+		}
+	}
+	api.tokens.tokens[value] = token{
+		auth:    auth,
+		expires: now.Add(TokenDuration),
+	}
+	return value
+}
+
+func (api *API) checkToken(value string) *Auth {
+	api.tokens.Lock()
+	defer api.tokens.Unlock()
+	tok, ok := api.tokens.tokens[value]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(tok.expires) {
+		delete(api.tokens.tokens, value)
+		return nil
+	}
+	auth := tok.auth
+	return &auth
+}
+
+func (api *API) removeToken(value string) {
+	api.tokens.Lock()
+	defer api.tokens.Unlock()
+	delete(api.tokens.tokens, value)
+}
+
+//RevokeUserTokens revoke the tokens and close the event streams of a given user, its access groups or privilege changed
+func (api *API) RevokeUserTokens(userHash string) {
+	api.tokens.Lock()
+	for key, tok := range api.tokens.tokens {
+		if tok.auth.UserHash == userHash {
+			delete(api.tokens.tokens, key)
+		}
+	}
+	api.tokens.Unlock()
+	api.closeUserWebsockets(userHash)
+}
+
+func bearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	return ""
+}
+
+func (api *API) authenticate(req *http.Request, queryToken bool) *Auth {
+	value := bearerToken(req)
+	if value == "" && queryToken {
+		value = req.URL.Query().Get("token")
+	}
+	if value != "" {
+		return api.checkToken(value)
+	}
+	login, password, ok := req.BasicAuth()
+	if ok {
+		return api.checkCredentials(login, password)
+	}
+	return nil
+}
+
+//authorize wrap a handler with the authentication and privilege checks
+func (api *API) authorize(privilege string, handler http.HandlerFunc) http.HandlerFunc {
+	return api.authorizeRequest(privilege, handler, false)
+}
+
+//authorizeWebsocket same as authorize but also accept the token in the query string:
+//browsers cannot set headers on websocket connections
+func (api *API) authorizeWebsocket(privilege string, handler http.HandlerFunc) http.HandlerFunc {
+	return api.authorizeRequest(privilege, handler, true)
+}
+
+func (api *API) authorizeRequest(privilege string, handler http.HandlerFunc, queryToken bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		api.setDefaultHeader(w)
+		auth := api.authenticate(req, queryToken)
+		if auth == nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="swh200"`)
+			api.sendError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if !auth.HasPrivilege(privilege) {
+			api.sendError(w, http.StatusForbidden, "User "+auth.Login+" is not allowed to perform this action")
+			return
+		}
+		ctx := context.WithValue(req.Context(), authKey, *auth)
+		handler(w, req.WithContext(ctx))
+	}
+}
+
+func getAuth(req *http.Request) Auth {
+	auth, _ := req.Context().Value(authKey).(Auth)
+	return auth
+}
+
+func (api *API) userLogin(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	login, password, ok := req.BasicAuth()
+	if !ok {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Error reading request body")
+			return
+		}
+		var cred APICredentials
+		err = json.Unmarshal(body, &cred)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+			return
+		}
+		login = cred.Login
+		password = cred.Password
+	}
+	auth := api.checkCredentials(login, password)
+	if auth == nil {
+		api.sendError(w, http.StatusUnauthorized, "Invalid login or password")
+		return
+	}
+	tok := APIToken{
+		AccessToken: api.newToken(*auth),
+		TokenType:   "bearer",
+		ExpiresIn:   int(TokenDuration.Seconds()),
+		Privilege:   auth.Privilege,
+	}
+	api.writeJSON(w, tok)
+}
+
+func (api *API) userLogout(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	value := bearerToken(req)
+	if value != "" {
+		api.removeToken(value)
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) getV1UserInfo(w http.ResponseWriter, req *http.Request) {
+	auth := getAuth(req)
+	var groups []int
+	for gr := range auth.AccessGroups {
+		groups = append(groups, gr)
+	}
+	info := APIUser{
+		UserHash:     auth.UserHash,
+		AccessGroups: groups,
+		Privilege:    auth.Privilege,
+	}
+	api.writeJSON(w, info)
+}
+
+func (api *API) getV1Users(w http.ResponseWriter, req *http.Request) {
+	privileges := database.GetUsersPrivilege(api.db)
+	users := make(map[string]APIUser)
+	for hash, user := range database.GetUsers(api.db) {
+		privilege := DefaultPrivilege
+		if priv, ok := privileges[hash]; ok && privilegeLevel(priv.Privilege) > 0 {
+			privilege = priv.Privilege
+		}
+		users[hash] = APIUser{
+			UserHash:     hash,
+			AccessGroups: user.AccessGroups,
+			Privilege:    privilege,
+		}
+	}
+	api.writeJSON(w, users)
+}
+
+func (api *API) setV1UserPrivilege(w http.ResponseWriter, req *http.Request) {
+	hash := mux.Vars(req)["hash"]
+	if database.GetUser(api.db, hash) == nil {
+		api.sendError(w, http.StatusNotFound, "User "+hash+" not found")
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Error reading request body")
+		return
+	}
+	var privilege database.UserPrivilege
+	err = json.Unmarshal(body, &privilege)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+		return
+	}
+	if privilegeLevel(privilege.Privilege) == 0 {
+		api.sendError(w, http.StatusBadRequest, "Unknown privilege "+privilege.Privilege)
+		return
+	}
+	privilege.UserHash = hash
+	err = database.SaveUserPrivilege(api.db, privilege)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+		return
+	}
+	api.RevokeUserTokens(hash)
+	w.Write([]byte("{}"))
+}
+
+func (api *API) setV1UserCredential(w http.ResponseWriter, req *http.Request) {
+	hash := mux.Vars(req)["hash"]
+	if database.GetUser(api.db, hash) == nil {
+		api.sendError(w, http.StatusNotFound, "User "+hash+" not found")
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Error reading request body")
+		return
+	}
+	var credential APIUserCredential
+	err = json.Unmarshal(body, &credential)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+		return
+	}
+	if credential.Login == "" || credential.Login == AdminLogin || credential.Password == "" {
+		api.sendError(w, http.StatusBadRequest, "Invalid login or password")
+		return
+	}
+	existing := database.GetUserCredential(api.db, credential.Login)
+	if existing != nil && existing.UserHash != hash {
+		api.sendError(w, http.StatusConflict, "Login "+credential.Login+" already used")
+		return
+	}
+	cred, err := newUserCredential(hash, credential.Login, credential.Password)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot generate salt "+err.Error())
+		return
+	}
+	err = database.SaveUserCredential(api.db, cred)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+		return
+	}
+	api.RevokeUserTokens(hash)
+	w.Write([]byte("{}"))
+}
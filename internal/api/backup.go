@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+func (api *API) getV1Backup(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	w.Header().Set("Content-Disposition", "attachment; filename=\"switch-backup.json\"")
+	api.writeJSON(w, api.core.ExportConfig())
+}
+
+func (api *API) restoreV1Backup(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Error reading request body")
+		return
+	}
+	var backup database.ConfigBackup
+	err = json.Unmarshal(body, &backup)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+		return
+	}
+	err = backup.Check()
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = api.core.RestoreConfig(backup)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot restore configuration "+err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+func (api *API) getV1GroupCalibration(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, ok := api.getGroupID(w, req)
+	if !ok {
+		return
+	}
+	calib := database.GetGroupCalibration(api.db, grID)
+	if calib == nil {
+		api.sendError(w, http.StatusNotFound, "No calibration for group "+strconv.Itoa(grID))
+		return
+	}
+	api.writeJSON(w, calib)
+}
+
+func (api *API) startV1GroupCalibration(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, ok := api.getGroupID(w, req)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Error reading request body")
+		return
+	}
+	var calib database.CalibrationRequest
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &calib)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+			return
+		}
+	}
+	err = api.core.StartGroupCalibration(grID, calib)
+	if err != nil {
+		api.sendError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+const (
+	defaultEnergyRange = 24 * time.Hour
+)
+
+//parseEnergyDate read an optional RFC3339 date parameter
+func parseEnergyDate(req *http.Request, name string, def time.Time) (time.Time, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (api *API) getV1Energy(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = database.EnergyHour
+	}
+	if _, ok := database.EnergyPeriods[period]; !ok {
+		api.sendError(w, http.StatusBadRequest, "Unknown period "+period)
+		return
+	}
+	to, err := parseEnergyDate(req, "to", time.Now().UTC())
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Invalid to date "+err.Error())
+		return
+	}
+	from, err := parseEnergyDate(req, "from", to.Add(-defaultEnergyRange))
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Invalid from date "+err.Error())
+		return
+	}
+	if from.After(to) {
+		api.sendError(w, http.StatusBadRequest, "from date is after to date")
+		return
+	}
+
+	auth := getAuth(req)
+	accept := auth.HasGroupAccess
+	if value := req.URL.Query().Get("group"); value != "" {
+		grID, err := strconv.Atoi(value)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Invalid group "+value)
+			return
+		}
+		if !auth.HasGroupAccess(grID) {
+			api.sendError(w, http.StatusForbidden, "Group "+value+" not allowed")
+			return
+		}
+		accept = func(id int) bool { return id == grID }
+	}
+
+	buckets := []database.EnergyBucket{}
+	for _, bucket := range api.core.GetEnergy(period, from, to) {
+		buckets = append(buckets, bucket.Filter(accept))
+	}
+	api.writeJSON(w, buckets)
+}
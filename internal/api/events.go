@@ -0,0 +1,191 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/romana/rlog"
+)
+
+const (
+	EventGroupStatus  = "groupStatus"
+	EventDriverHello  = "driverHello"
+	EventDriverStatus = "driverStatus"
+	EventDriverLost   = "driverLost"
+	EventAlarm        = "alarm"
+
+	eventsQueueSize   = 256
+	clientQueueSize   = 64
+	eventWriteTimeout = 5 * time.Second
+)
+
+//Event pushed on the /events websocket
+type Event struct {
+	Type       string      `json:"type"`
+	Date       string      `json:"date"`
+	Group      *int        `json:"group,omitempty"`
+	DriverType string      `json:"driverType,omitempty"`
+	Mac        string      `json:"mac,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+//wsClient websocket connected client, the events are written by its own goroutine
+type wsClient struct {
+	conn *websocket.Conn
+	auth Auth
+	send chan Event
+}
+
+//wsClients websocket connected clients
+type wsClients struct {
+	sync.Mutex
+	clients map[*wsClient]bool
+}
+
+//SendGroupEvent push a group event to the websocket clients
+func (api *API) SendGroupEvent(evtType string, grID int, data interface{}) {
+	api.sendEvent(Event{
+		Type:  evtType,
+		Group: &grID,
+		Data:  data,
+	})
+}
+
+//SendDriverEvent push a driver event to the websocket clients, grID is nil for drivers without group
+func (api *API) SendDriverEvent(evtType string, driverType string, mac string, grID *int, data interface{}) {
+	api.sendEvent(Event{
+		Type:       evtType,
+		Group:      grID,
+		DriverType: driverType,
+		Mac:        mac,
+		Data:       data,
+	})
+}
+
+//SendAlarmEvent push a raised or cleared alarm to the websocket clients, grID is nil for drivers without group
+func (api *API) SendAlarmEvent(grID *int, data interface{}) {
+	api.sendEvent(Event{
+		Type:  EventAlarm,
+		Group: grID,
+		Data:  data,
+	})
+}
+
+func (api *API) sendEvent(evt Event) {
+	evt.Date = time.Now().UTC().Format(time.RFC3339)
+	select {
+	case api.eventsAPI <- evt:
+	default:
+		// never block the caller (group loop or MQTT callback)
+		rlog.Warn("Events queue full, drop " + evt.Type + " event")
+	}
+}
+
+func (api *API) websocketEvents() {
+	for evt := range api.eventsAPI {
+		api.wsClients.Lock()
+		for client := range api.wsClients.clients {
+			if evt.Group != nil && !client.auth.HasGroupAccess(*evt.Group) {
+				continue
+			}
+			select {
+			case client.send <- evt:
+			default:
+				// a slow client must not delay the others
+				rlog.Warn("Websocket client " + client.auth.Login + " too slow, disconnect it")
+				api.closeWebsocketClient(client)
+			}
+		}
+		api.wsClients.Unlock()
+	}
+}
+
+//closeWebsocketClient must be called with the clients lock held
+func (api *API) closeWebsocketClient(client *wsClient) {
+	if _, ok := api.wsClients.clients[client]; !ok {
+		return
+	}
+	delete(api.wsClients.clients, client)
+	close(client.send)
+	client.conn.Close()
+}
+
+func (api *API) removeWebsocketClient(client *wsClient) {
+	api.wsClients.Lock()
+	defer api.wsClients.Unlock()
+	api.closeWebsocketClient(client)
+}
+
+//closeUserWebsockets disconnect the event streams opened with the previous rights of a user
+func (api *API) closeUserWebsockets(userHash string) {
+	api.wsClients.Lock()
+	defer api.wsClients.Unlock()
+	for client := range api.wsClients.clients {
+		if client.auth.UserHash == userHash {
+			rlog.Info("Websocket client " + client.auth.Login + " rights revoked, disconnect it")
+			api.closeWebsocketClient(client)
+		}
+	}
+}
+
+func (api *API) writeWebsocketEvents(client *wsClient) {
+	for evt := range client.send {
+		client.conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		err := client.conn.WriteJSON(evt)
+		if err != nil {
+			rlog.Warn("Websocket client " + client.auth.Login + " disconnected: " + err.Error())
+			api.removeWebsocketClient(client)
+			return
+		}
+	}
+}
+
+//checkOrigin allow the websocket connections from pages served by the API host and from
+//non browser clients (no Origin header)
+func (api *API) checkOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, req.Host) {
+		return true
+	}
+	return api.apiIP != "" && strings.EqualFold(u.Hostname(), api.apiIP)
+}
+
+func (api *API) webEvents(w http.ResponseWriter, req *http.Request) {
+	auth := getAuth(req)
+	conn, err := api.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		rlog.Error("Error when switching in websocket " + err.Error())
+		return
+	}
+	client := &wsClient{
+		conn: conn,
+		auth: auth,
+		send: make(chan Event, clientQueueSize),
+	}
+	api.wsClients.Lock()
+	api.wsClients.clients[client] = true
+	api.wsClients.Unlock()
+	rlog.Info("Websocket client " + auth.Login + " connected")
+
+	go api.writeWebsocketEvents(client)
+	go func() {
+		// only used to detect the client disconnection
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				api.removeWebsocketClient(client)
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/gorilla/mux"
+)
+
+func (api *API) getV1Groups(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	groups := make(map[int]database.GroupStatus)
+	for grID, group := range api.core.GetGroupsStatus() {
+		if auth.HasGroupAccess(grID) {
+			groups[grID] = group
+		}
+	}
+	api.writeJSON(w, groups)
+}
+
+func (api *API) getV1Group(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Invalid group "+mux.Vars(req)["id"])
+		return
+	}
+	group, ok := api.core.GetGroupsStatus()[grID]
+	if !ok {
+		api.sendError(w, http.StatusNotFound, "Group "+strconv.Itoa(grID)+" not found")
+		return
+	}
+	if !getAuth(req).HasGroupAccess(grID) {
+		api.sendError(w, http.StatusForbidden, "Group "+strconv.Itoa(grID)+" not allowed")
+		return
+	}
+	api.writeJSON(w, group)
+}
+
+func (api *API) setV1GroupCommand(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Invalid group "+mux.Vars(req)["id"])
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Error reading request body")
+		return
+	}
+	var cmd map[string]interface{}
+	err = json.Unmarshal(body, &cmd)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+		return
+	}
+	if _, ok := api.core.GetGroupsStatus()[grID]; !ok {
+		api.sendError(w, http.StatusNotFound, "Group "+strconv.Itoa(grID)+" not found")
+		return
+	}
+	if !getAuth(req).HasGroupAccess(grID) {
+		api.sendError(w, http.StatusForbidden, "Group "+strconv.Itoa(grID)+" not allowed")
+		return
+	}
+	err = api.core.SendGroupCommand(grID, body)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/gorilla/mux"
+)
+
+//handleGroupSettings register the read, update and remove handlers of a per group settings table
+func (api *API) handleGroupSettings(router *mux.Router, path string, table database.GroupSettingsTable) {
+	router.HandleFunc(path, api.authorize(PrivilegeReader, api.getV1GroupSettings(table))).Methods("GET")
+	router.HandleFunc(path, api.authorize(PrivilegeOperator, api.setV1GroupSettings(table))).Methods("POST")
+	router.HandleFunc(path, api.authorize(PrivilegeOperator, api.removeV1GroupSettings(table))).Methods("DELETE")
+}
+
+func (api *API) getV1GroupSettings(table database.GroupSettingsTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		api.setDefaultHeader(w)
+		grID, ok := api.getGroupID(w, req)
+		if !ok {
+			return
+		}
+		cfg := database.GetGroupSettings(api.db, table, grID)
+		if cfg == nil {
+			cfg = table.New(grID)
+		}
+		api.writeJSON(w, cfg)
+	}
+}
+
+func (api *API) setV1GroupSettings(table database.GroupSettingsTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		api.setDefaultHeader(w)
+		grID, ok := api.getGroupID(w, req)
+		if !ok {
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Error reading request body")
+			return
+		}
+		cfg := table.New(grID)
+		err = json.Unmarshal(body, cfg)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+			return
+		}
+		cfg.SetGroup(grID)
+		err = cfg.Check()
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		err = database.SaveGroupSettings(api.db, table, grID, cfg)
+		if err != nil {
+			api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+			return
+		}
+		api.core.ReloadGroupSettings(table, grID)
+		w.Write([]byte("{}"))
+	}
+}
+
+func (api *API) removeV1GroupSettings(table database.GroupSettingsTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		api.setDefaultHeader(w)
+		grID, ok := api.getGroupID(w, req)
+		if !ok {
+			return
+		}
+		err := database.RemoveGroupSettings(api.db, table, grID)
+		if err != nil {
+			api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+			return
+		}
+		api.core.ReloadGroupSettings(table, grID)
+		w.Write([]byte("{}"))
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+const (
+	defaultJournalLimit = 500
+)
+
+func (api *API) getV1Journal(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	query := req.URL.Query()
+	filter := database.JournalFilter{
+		Mac:        strings.ToUpper(query.Get("mac")),
+		DriverType: query.Get("driverType"),
+		Event:      query.Get("event"),
+	}
+	limit := defaultJournalLimit
+	var err error
+	if value := query.Get("from"); value != "" {
+		filter.From, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Invalid from date "+err.Error())
+			return
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		filter.To, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Invalid to date "+err.Error())
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			api.sendError(w, http.StatusBadRequest, "Invalid limit "+value)
+			return
+		}
+	}
+	auth := getAuth(req)
+	if value := query.Get("group"); value != "" {
+		grID, err := strconv.Atoi(value)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Invalid group "+value)
+			return
+		}
+		if !auth.HasGroupAccess(grID) {
+			api.sendError(w, http.StatusForbidden, "Group "+value+" not allowed")
+			return
+		}
+		filter.Group = &grID
+	}
+
+	//the limit applies to the entries allowed for the user
+	entries := []database.JournalEntry{}
+	for _, entry := range database.GetJournalEntries(api.db, filter) {
+		if entry.Group != nil && !auth.HasGroupAccess(*entry.Group) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	api.writeJSON(w, entries)
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	metricsPrefix      = "swh200_"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+//GroupTickMetrics processing time of the group loop ticks
+type GroupTickMetrics struct {
+	Count uint64  //number of ticks
+	Sum   float64 //s, total processing time
+	Last  float64 //s, processing time of the last tick
+}
+
+//CoreMetrics counters maintained by the switch core service
+type CoreMetrics struct {
+	DriversLost map[string]uint64        //per driver type
+	SendErrors  map[string]uint64        //per broker: local, server or cluster
+	GroupTicks  map[int]GroupTickMetrics //per group
+}
+
+//metricsWriter Prometheus text exposition format writer
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+//family write the HELP and TYPE lines of a metric
+func (m *metricsWriter) family(name, kind, help string) {
+	fmt.Fprintf(&m.buf, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+}
+
+//sample write a metric value, labels are given as name/value pairs
+func (m *metricsWriter) sample(name string, value float64, labels ...string) {
+	m.buf.WriteString(metricsPrefix + name)
+	if len(labels) > 0 {
+		var elts []string
+		for i := 0; i+1 < len(labels); i += 2 {
+			elts = append(elts, labels[i]+"=\""+escapeLabel(labels[i+1])+"\"")
+		}
+		m.buf.WriteString("{" + strings.Join(elts, ",") + "}")
+	}
+	m.buf.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+func escapeLabel(value string) string {
+	value = strings.Replace(value, "\\", "\\\\", -1)
+	value = strings.Replace(value, "\"", "\\\"", -1)
+	return strings.Replace(value, "\n", "\\n", -1)
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(values map[string]uint64) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type driverMetric struct {
+	kind  string
+	mac   string
+	group int
+	power int
+}
+
+func (api *API) getMetrics(w http.ResponseWriter, req *http.Request) {
+	auth := getAuth(req)
+	m := metricsWriter{}
+
+	var drivers []driverMetric
+	counts := make(map[string]int)
+	for mac, driver := range api.core.GetLeds() {
+		counts["led"]++
+		if auth.HasGroupAccess(driver.Group) {
+			drivers = append(drivers, driverMetric{"led", mac, driver.Group, driver.LinePower})
+		}
+	}
+	for mac, driver := range api.core.GetBlinds() {
+		counts["blind"]++
+		if auth.HasGroupAccess(driver.Group) {
+			drivers = append(drivers, driverMetric{"blind", mac, driver.Group, driver.LinePower})
+		}
+	}
+	for mac, driver := range api.core.GetHvacs() {
+		counts["hvac"]++
+		if auth.HasGroupAccess(driver.Group) {
+			drivers = append(drivers, driverMetric{"hvac", mac, driver.Group, driver.LinePower})
+		}
+	}
+	sensors := api.core.GetSensors()
+	counts["sensor"] = len(sensors)
+	nanos := api.core.GetNanos()
+	counts["nano"] = len(nanos)
+	counts["wago"] = len(api.core.GetWagos())
+	sort.Slice(drivers, func(i, j int) bool { return drivers[i].mac < drivers[j].mac })
+
+	m.family("drivers", "gauge", "Number of drivers currently seen by type")
+	for _, kind := range []string{"led", "sensor", "blind", "hvac", "wago", "nano"} {
+		m.sample("drivers", float64(counts[kind]), "type", kind)
+	}
+
+	m.family("driver_line_power_watts", "gauge", "Line power of the drivers")
+	for _, driver := range drivers {
+		m.sample("driver_line_power_watts", float64(driver.power), "type", driver.kind, "mac", driver.mac, "group", strconv.Itoa(driver.group))
+	}
+
+	var macs []string
+	for mac, driver := range sensors {
+		if auth.HasGroupAccess(driver.Group) {
+			macs = append(macs, mac)
+		}
+	}
+	sort.Strings(macs)
+	m.family("sensor_temperature_celsius", "gauge", "Temperature measured by the sensors")
+	for _, mac := range macs {
+		driver := sensors[mac]
+		m.sample("sensor_temperature_celsius", float64(driver.Temperature)/10, "mac", mac, "group", strconv.Itoa(driver.Group))
+	}
+
+	macs = nil
+	for mac, driver := range nanos {
+		if auth.HasGroupAccess(driver.Group) {
+			macs = append(macs, mac)
+		}
+	}
+	sort.Strings(macs)
+	m.family("nanosense_temperature_celsius", "gauge", "Temperature measured by the nanosenses")
+	for _, mac := range macs {
+		m.sample("nanosense_temperature_celsius", float64(nanos[mac].Temperature)/10, "mac", mac, "group", strconv.Itoa(nanos[mac].Group))
+	}
+	m.family("nanosense_co2_ppm", "gauge", "CO2 measured by the nanosenses")
+	for _, mac := range macs {
+		m.sample("nanosense_co2_ppm", float64(nanos[mac].CO2), "mac", mac, "group", strconv.Itoa(nanos[mac].Group))
+	}
+	m.family("nanosense_cov_ppm", "gauge", "COV measured by the nanosenses")
+	for _, mac := range macs {
+		m.sample("nanosense_cov_ppm", float64(nanos[mac].COV), "mac", mac, "group", strconv.Itoa(nanos[mac].Group))
+	}
+
+	var groups []int
+	status := api.core.GetGroupsStatus()
+	for grID := range status {
+		if auth.HasGroupAccess(grID) {
+			groups = append(groups, grID)
+		}
+	}
+	sort.Ints(groups)
+	m.family("group_brightness_lux", "gauge", "Brightness of the groups")
+	for _, grID := range groups {
+		m.sample("group_brightness_lux", float64(status[grID].Brightness), "group", strconv.Itoa(grID))
+	}
+	m.family("group_presence", "gauge", "Presence detected in the groups")
+	for _, grID := range groups {
+		m.sample("group_presence", boolValue(status[grID].Presence), "group", strconv.Itoa(grID))
+	}
+	m.family("group_setpoint_leds_percent", "gauge", "LEDs setpoint of the groups")
+	for _, grID := range groups {
+		m.sample("group_setpoint_leds_percent", float64(status[grID].SetpointLeds), "group", strconv.Itoa(grID))
+	}
+	m.family("group_auto", "gauge", "Groups in automatic mode")
+	for _, grID := range groups {
+		m.sample("group_auto", boolValue(status[grID].Auto), "group", strconv.Itoa(grID))
+	}
+	m.family("group_temperature_celsius", "gauge", "Temperature of the groups")
+	for _, grID := range groups {
+		m.sample("group_temperature_celsius", float64(status[grID].Temperature)/10, "group", strconv.Itoa(grID))
+	}
+	m.family("group_co2_ppm", "gauge", "CO2 of the groups")
+	for _, grID := range groups {
+		m.sample("group_co2_ppm", float64(status[grID].CO2), "group", strconv.Itoa(grID))
+	}
+	m.family("group_cov_ppm", "gauge", "COV of the groups")
+	for _, grID := range groups {
+		m.sample("group_cov_ppm", float64(status[grID].COV), "group", strconv.Itoa(grID))
+	}
+
+	core := api.core.GetMetrics()
+	m.family("drivers_lost_total", "counter", "Drivers no longer seen by type")
+	for _, kind := range sortedKeys(core.DriversLost) {
+		m.sample("drivers_lost_total", float64(core.DriversLost[kind]), "type", kind)
+	}
+	m.family("mqtt_send_errors_total", "counter", "MQTT messages that could not be sent by broker")
+	for _, broker := range sortedKeys(core.SendErrors) {
+		m.sample("mqtt_send_errors_total", float64(core.SendErrors[broker]), "broker", broker)
+	}
+
+	groups = nil
+	for grID := range core.GroupTicks {
+		if auth.HasGroupAccess(grID) {
+			groups = append(groups, grID)
+		}
+	}
+	sort.Ints(groups)
+	m.family("group_tick_duration_seconds", "summary", "Processing time of the group loop ticks")
+	for _, grID := range groups {
+		m.sample("group_tick_duration_seconds_sum", core.GroupTicks[grID].Sum, "group", strconv.Itoa(grID))
+		m.sample("group_tick_duration_seconds_count", float64(core.GroupTicks[grID].Count), "group", strconv.Itoa(grID))
+	}
+	m.family("group_last_tick_duration_seconds", "gauge", "Processing time of the last group loop tick")
+	for _, grID := range groups {
+		m.sample("group_last_tick_duration_seconds", core.GroupTicks[grID].Last, "group", strconv.Itoa(grID))
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+	w.Write(m.buf.Bytes())
+}
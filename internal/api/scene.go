@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/gorilla/mux"
+)
+
+func (api *API) getV1GroupScenes(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, ok := api.getGroupID(w, req)
+	if !ok {
+		return
+	}
+	api.writeJSON(w, database.GetGroupScenes(api.db, grID))
+}
+
+func (api *API) setV1GroupScene(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, ok := api.getGroupID(w, req)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Error reading request body")
+		return
+	}
+	var scene database.GroupScene
+	err = json.Unmarshal(body, &scene)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Could not parse input format "+err.Error())
+		return
+	}
+	scene.Group = grID
+	scene.Scene = mux.Vars(req)["scene"]
+	err = scene.Check()
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = database.SaveGroupScene(api.db, scene)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) removeV1GroupScene(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, ok := api.getGroupID(w, req)
+	if !ok {
+		return
+	}
+	sceneID := mux.Vars(req)["scene"]
+	if database.GetGroupScene(api.db, grID, sceneID) == nil {
+		api.sendError(w, http.StatusNotFound, "Scene "+sceneID+" not found")
+		return
+	}
+	err := database.RemoveGroupScene(api.db, grID, sceneID)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Cannot update database "+err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) recallV1GroupScene(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	grID, ok := api.getGroupID(w, req)
+	if !ok {
+		return
+	}
+	sceneID := mux.Vars(req)["scene"]
+	if database.GetGroupScene(api.db, grID, sceneID) == nil {
+		api.sendError(w, http.StatusNotFound, "Scene "+sceneID+" not found")
+		return
+	}
+	err := api.core.RecallGroupScene(grID, sceneID)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Write([]byte("{}"))
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/gorilla/mux"
+)
+
+//getGroupID parse the group of the request and check that the user is allowed on it
+func (api *API) getGroupID(w http.ResponseWriter, req *http.Request) (int, bool) {
+	grID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, "Invalid group "+mux.Vars(req)["id"])
+		return 0, false
+	}
+	cfg, _ := database.GetGroupConfig(api.db, grID)
+	if cfg == nil {
+		api.sendError(w, http.StatusNotFound, "Group "+strconv.Itoa(grID)+" not found")
+		return 0, false
+	}
+	if !getAuth(req).HasGroupAccess(grID) {
+		api.sendError(w, http.StatusForbidden, "Group "+strconv.Itoa(grID)+" not allowed")
+		return 0, false
+	}
+	return grID, true
+}
+
+func (api *API) getV1Schedules(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	schedules := make(map[int]database.GroupSchedule)
+	for grID, sched := range database.GetGroupsSchedule(api.db) {
+		if auth.HasGroupAccess(grID) {
+			schedules[grID] = sched
+		}
+	}
+	api.writeJSON(w, schedules)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/energieip/common-components-go/pkg/dblind"
+	"github.com/energieip/common-components-go/pkg/dhvac"
+	dl "github.com/energieip/common-components-go/pkg/dled"
+	dn "github.com/energieip/common-components-go/pkg/dnanosense"
+	ds "github.com/energieip/common-components-go/pkg/dsensor"
+	"github.com/gorilla/mux"
+)
+
+func (api *API) getV1Leds(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	drivers := make(map[string]dl.Led)
+	for mac, driver := range api.core.GetLeds() {
+		if auth.HasGroupAccess(driver.Group) {
+			drivers[mac] = driver
+		}
+	}
+	api.writeJSON(w, drivers)
+}
+
+func (api *API) getV1Led(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	mac := strings.ToUpper(mux.Vars(req)["mac"])
+	driver, ok := api.core.GetLeds()[mac]
+	if !ok {
+		api.sendError(w, http.StatusNotFound, "LED "+mac+" not found")
+		return
+	}
+	if !getAuth(req).HasGroupAccess(driver.Group) {
+		api.sendError(w, http.StatusForbidden, "LED "+mac+" not allowed")
+		return
+	}
+	api.writeJSON(w, driver)
+}
+
+func (api *API) getV1Sensors(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	drivers := make(map[string]ds.Sensor)
+	for mac, driver := range api.core.GetSensors() {
+		if auth.HasGroupAccess(driver.Group) {
+			drivers[mac] = driver
+		}
+	}
+	api.writeJSON(w, drivers)
+}
+
+func (api *API) getV1Sensor(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	mac := strings.ToUpper(mux.Vars(req)["mac"])
+	driver, ok := api.core.GetSensors()[mac]
+	if !ok {
+		api.sendError(w, http.StatusNotFound, "Sensor "+mac+" not found")
+		return
+	}
+	if !getAuth(req).HasGroupAccess(driver.Group) {
+		api.sendError(w, http.StatusForbidden, "Sensor "+mac+" not allowed")
+		return
+	}
+	api.writeJSON(w, driver)
+}
+
+func (api *API) getV1Blinds(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	drivers := make(map[string]dblind.Blind)
+	for mac, driver := range api.core.GetBlinds() {
+		if auth.HasGroupAccess(driver.Group) {
+			drivers[mac] = driver
+		}
+	}
+	api.writeJSON(w, drivers)
+}
+
+func (api *API) getV1Blind(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	mac := strings.ToUpper(mux.Vars(req)["mac"])
+	driver, ok := api.core.GetBlinds()[mac]
+	if !ok {
+		api.sendError(w, http.StatusNotFound, "Blind "+mac+" not found")
+		return
+	}
+	if !getAuth(req).HasGroupAccess(driver.Group) {
+		api.sendError(w, http.StatusForbidden, "Blind "+mac+" not allowed")
+		return
+	}
+	api.writeJSON(w, driver)
+}
+
+func (api *API) getV1Hvacs(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	drivers := make(map[string]dhvac.Hvac)
+	for mac, driver := range api.core.GetHvacs() {
+		if auth.HasGroupAccess(driver.Group) {
+			drivers[mac] = driver
+		}
+	}
+	api.writeJSON(w, drivers)
+}
+
+func (api *API) getV1Hvac(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	mac := strings.ToUpper(mux.Vars(req)["mac"])
+	driver, ok := api.core.GetHvacs()[mac]
+	if !ok {
+		api.sendError(w, http.StatusNotFound, "HVAC "+mac+" not found")
+		return
+	}
+	if !getAuth(req).HasGroupAccess(driver.Group) {
+		api.sendError(w, http.StatusForbidden, "HVAC "+mac+" not allowed")
+		return
+	}
+	api.writeJSON(w, driver)
+}
+
+func (api *API) getV1Wagos(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	api.writeJSON(w, api.core.GetWagos())
+}
+
+func (api *API) getV1Wago(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	mac := strings.ToUpper(mux.Vars(req)["mac"])
+	driver, ok := api.core.GetWagos()[mac]
+	if !ok {
+		api.sendError(w, http.StatusNotFound, "WAGO "+mac+" not found")
+		return
+	}
+	api.writeJSON(w, driver)
+}
+
+func (api *API) getV1Nanos(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	auth := getAuth(req)
+	drivers := make(map[string]dn.Nanosense)
+	for mac, driver := range api.core.GetNanos() {
+		if auth.HasGroupAccess(driver.Group) {
+			drivers[mac] = driver
+		}
+	}
+	api.writeJSON(w, drivers)
+}
+
+func (api *API) getV1Nano(w http.ResponseWriter, req *http.Request) {
+	api.setDefaultHeader(w)
+	mac := strings.ToUpper(mux.Vars(req)["mac"])
+	driver, ok := api.core.GetNanos()[mac]
+	if !ok {
+		api.sendError(w, http.StatusNotFound, "Nanosense "+mac+" not found")
+		return
+	}
+	if !getAuth(req).HasGroupAccess(driver.Group) {
+		api.sendError(w, http.StatusForbidden, "Nanosense "+mac+" not allowed")
+		return
+	}
+	api.writeJSON(w, driver)
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/energieip/common-components-go/pkg/dhvac"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+//AirQuality indoor air quality ventilation state of a group
+type AirQuality struct {
+	Index   *int           //nil when the rules are disabled
+	Running bool           //ventilation forced
+	Since   time.Time      //start of the ventilation
+	Demand  int            //damper position requested by the ventilation in %, 0 when stopped
+	Damper  *int           //last damper position sent while the thermal control is not running
+	Windows map[string]int //window actuators opened by the ventilation by blind driver MAC
+}
+
+//airQualitySettings return the air quality settings of the group or nil if none is set
+func (group *Group) airQualitySettings() *database.GroupAirQuality {
+	cfg, _ := group.settings(database.AirQualitySettings).(*database.GroupAirQuality)
+	return cfg
+}
+
+//ventilationDemand return the damper position proportional to the measure between the threshold and the maximum
+func ventilationDemand(value int, threshold int, max int, damperMin int) int {
+	if value < threshold {
+		return 0
+	}
+	if value >= max {
+		return 100
+	}
+	return damperMin + (100-damperMin)*(value-threshold)/(max-threshold)
+}
+
+//airQualityLevels check if the measures are above the thresholds, below the thresholds minus
+//the hysteresis and above the maximum values
+func airQualityLevels(cfg database.GroupAirQuality, co2 int, cov int) (bool, bool, bool) {
+	hysteresis := cfg.GetHysteresis()
+	above := co2 >= cfg.GetCO2Threshold()
+	below := co2 < cfg.GetCO2Threshold()*(100-hysteresis)/100
+	poor := co2 >= cfg.GetCO2Max()
+	if cfg.COVThreshold != nil {
+		above = above || cov >= *cfg.COVThreshold
+		below = below && cov < *cfg.COVThreshold*(100-hysteresis)/100
+		poor = poor || cov >= cfg.GetCOVMax()
+	}
+	return above, below, poor
+}
+
+//sendVentilationDamper forward the ventilation damper position to the group HVACs, the thermal
+//control merges it with its own demand while it is running
+func (s *Service) sendVentilationDamper(group *Group) {
+	airQuality := &group.AirQuality
+	if group.Thermal.Demand != nil {
+		airQuality.Damper = nil
+		return
+	}
+	if airQuality.Damper == nil && airQuality.Demand == 0 {
+		return
+	}
+	if airQuality.Damper != nil && *airQuality.Damper == airQuality.Demand {
+		return
+	}
+	damper := airQuality.Demand
+	airQuality.Damper = &damper
+	for _, mac := range group.Runtime.Hvacs {
+		_, ok := s.hvacs.Get(mac)
+		if !ok {
+			continue
+		}
+		//runtime command only: the ventilation demand is not part of the HVAC setup
+		forcingDamper := damper
+		s.sendHvacUpdate(dhvac.HvacConf{
+			Mac:           mac,
+			ForcingDamper: &forcingDamper,
+		})
+	}
+}
+
+//openVentilation raise the group blinds unless the sun protection keeps them down and open the window actuators
+func (s *Service) openVentilation(group *Group, cfg database.GroupAirQuality) {
+	if cfg.OpenBlinds && len(group.Runtime.Blinds) > 0 {
+		switch group.BlindAuto.Protection {
+		case database.BlindProtectionGlare, database.BlindProtectionHeat:
+		default:
+			blind := BlindUp
+			group.SetpointBlinds = &blind
+			group.SetpointSlatBlinds = nil
+			s.setpointBlind(group, &blind, nil)
+		}
+	}
+	if len(cfg.Windows) == 0 {
+		return
+	}
+	group.AirQuality.Windows = cfg.Windows
+	for mac, channel := range cfg.Windows {
+		position := BlindUp
+		s.sendBlindGroupSetpoint(mac, channel, &position, nil)
+	}
+}
+
+//closeVentilation close the window actuators opened by the ventilation
+func (s *Service) closeVentilation(group *Group) {
+	for mac, channel := range group.AirQuality.Windows {
+		position := BlindDown
+		s.sendBlindGroupSetpoint(mac, channel, &position, nil)
+	}
+	group.AirQuality.Windows = nil
+}
+
+//stopAirQuality release the dampers when the rules are disabled
+func (s *Service) stopAirQuality(group *Group) {
+	if group.AirQuality.Index == nil && !group.AirQuality.Running {
+		return
+	}
+	group.AirQuality.Index = nil
+	group.AirQuality.Running = false
+	group.AirQuality.Demand = 0
+	s.sendVentilationDamper(group)
+	s.closeVentilation(group)
+	group.AirQuality = AirQuality{}
+}
+
+//checkAirQuality force the HVACs dampers opening while the CO2 or the COV are too high
+func (s *Service) checkAirQuality(group *Group) {
+	cfg := group.airQualitySettings()
+	if cfg == nil || !cfg.Enabled || group.Nanosenses.Count()-group.NanosensesIssue.Count() <= 0 {
+		s.stopAirQuality(group)
+		return
+	}
+	now := s.clock.Now()
+	airQuality := &group.AirQuality
+	above, below, poor := airQualityLevels(*cfg, group.CO2, group.COV)
+	if !airQuality.Running && above {
+		airQuality.Running = true
+		airQuality.Since = now
+		rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : air quality ventilation started, CO2 " + strconv.Itoa(group.CO2) + " COV " + strconv.Itoa(group.COV))
+		s.openVentilation(group, *cfg)
+	} else if airQuality.Running && below && now.Sub(airQuality.Since) >= time.Duration(cfg.GetMinRunTime())*time.Second {
+		airQuality.Running = false
+		rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : air quality ventilation stopped")
+		s.closeVentilation(group)
+	}
+
+	index := database.AirQualityGood
+	airQuality.Demand = 0
+	if airQuality.Running {
+		index = database.AirQualityModerate
+		airQuality.Demand = cfg.GetDamperMin()
+		demand := ventilationDemand(group.CO2, cfg.GetCO2Threshold(), cfg.GetCO2Max(), cfg.GetDamperMin())
+		if demand > airQuality.Demand {
+			airQuality.Demand = demand
+		}
+		if cfg.COVThreshold != nil {
+			demand = ventilationDemand(group.COV, *cfg.COVThreshold, cfg.GetCOVMax(), cfg.GetDamperMin())
+			if demand > airQuality.Demand {
+				airQuality.Demand = demand
+			}
+		}
+	}
+	if poor {
+		index = database.AirQualityPoor
+	}
+	airQuality.Index = &index
+	s.sendVentilationDamper(group)
+}
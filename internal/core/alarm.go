@@ -0,0 +1,331 @@
+package core
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	genericNetwork "github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	UrlAlarm = "alarm"
+
+	alarmsInterval = 5 * time.Second
+)
+
+//SwitchAlarmRules alarm rules sent by the server
+type SwitchAlarmRules struct {
+	Rules map[string]database.AlarmRule `json:"rules"`
+}
+
+//alarmState debounce state of a rule on a group or a driver
+type alarmState struct {
+	since time.Time       //start of the pending raise or clear, zero when none
+	alarm *database.Alarm //raised alarm
+}
+
+type alarmsEngine struct {
+	mutex  sync.Mutex
+	states map[string]*alarmState //indexed by rule and target
+}
+
+//alarmTarget group or driver on which a rule is evaluated
+type alarmTarget struct {
+	key    string
+	group  *int
+	mac    string
+	values map[string]float64
+}
+
+func (s *Service) onUpdateAlarmRules(client genericNetwork.Client, msg genericNetwork.Message) {
+	payload := msg.Payload()
+	rlog.Debug(msg.Topic() + " : " + string(payload))
+	var config SwitchAlarmRules
+	err := json.Unmarshal(payload, &config)
+	if err != nil {
+		rlog.Error("Cannot parse alarm rules ", err.Error())
+		return
+	}
+	for name, rule := range config.Rules {
+		rule.Name = name
+		rule.Mac = strings.ToUpper(rule.Mac)
+		err = rule.Check()
+		if err != nil {
+			rlog.Error("Invalid alarm rule " + name + ": " + err.Error())
+			continue
+		}
+		err = database.SaveAlarmRule(s.db, rule)
+		if err != nil {
+			rlog.Error("Cannot save alarm rule " + name + ": " + err.Error())
+		}
+	}
+}
+
+func (s *Service) onRemoveAlarmRules(client genericNetwork.Client, msg genericNetwork.Message) {
+	payload := msg.Payload()
+	rlog.Debug(msg.Topic() + " : " + string(payload))
+	var config SwitchAlarmRules
+	err := json.Unmarshal(payload, &config)
+	if err != nil {
+		rlog.Error("Cannot parse alarm rules ", err.Error())
+		return
+	}
+	for name := range config.Rules {
+		database.RemoveAlarmRule(s.db, name)
+	}
+}
+
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+//groupAlarmValues return the group metrics measured by its drivers
+func groupAlarmValues(status gm.GroupStatus) map[string]float64 {
+	values := make(map[string]float64)
+	if len(status.Nanosenses) > 0 {
+		values[database.AlarmMetricCO2] = float64(status.CO2)
+		values[database.AlarmMetricCOV] = float64(status.COV)
+		values[database.AlarmMetricHygrometry] = float64(status.Hygrometry)
+	}
+	if len(status.Nanosenses) > 0 {
+		values[database.AlarmMetricTemperature] = float64(status.Temperature) / 10
+	} else if len(status.Sensors) > 0 {
+		//the sensors only measure the ceiling temperature
+		values[database.AlarmMetricTemperature] = float64(status.CeilingTemperature) / 10
+	}
+	if len(status.Sensors) > 0 {
+		values[database.AlarmMetricBrightness] = float64(status.Brightness)
+		values[database.AlarmMetricPresence] = boolMetric(status.Presence)
+	}
+	if len(status.Blinds) > 0 {
+		values[database.AlarmMetricWindowsOpened] = boolMetric(status.WindowsOpened)
+	}
+	if len(status.Hvacs) > 0 {
+		values[database.AlarmMetricHvacsHeatCool] = float64(status.HvacsHeatCool)
+	}
+	return values
+}
+
+//driverAlarmTarget read the driver metrics from its status
+func driverAlarmTarget(mac string, driver interface{}) alarmTarget {
+	var status struct {
+		Group     *int `json:"group"`
+		Error     int  `json:"error"`
+		LinePower *int `json:"linePower"`
+	}
+	inrec, _ := json.Marshal(driver)
+	json.Unmarshal(inrec, &status)
+	target := alarmTarget{
+		key:    mac,
+		group:  status.Group,
+		mac:    mac,
+		values: make(map[string]float64),
+	}
+	target.values[database.AlarmMetricError] = float64(status.Error)
+	if status.LinePower != nil {
+		target.values[database.AlarmMetricLinePower] = float64(*status.LinePower)
+	}
+	return target
+}
+
+//alarmTargets return the groups or the drivers concerned by a rule
+func (s *Service) alarmTargets(rule database.AlarmRule) []alarmTarget {
+	var targets []alarmTarget
+	if rule.IsGroupRule() {
+		for _, elt := range s.groupStatus.Items() {
+			status, err := gm.ToGroupStatus(elt)
+			if err != nil {
+				continue
+			}
+			if rule.Group != nil && *rule.Group != status.Group {
+				continue
+			}
+			grID := status.Group
+			targets = append(targets, alarmTarget{
+				key:    "group" + strconv.Itoa(grID),
+				group:  &grID,
+				values: groupAlarmValues(*status),
+			})
+		}
+		return targets
+	}
+
+	drivers := make(map[string]interface{})
+	for mac, driver := range s.GetLeds() {
+		drivers[mac] = driver
+	}
+	for mac, driver := range s.GetSensors() {
+		drivers[mac] = driver
+	}
+	for mac, driver := range s.GetBlinds() {
+		drivers[mac] = driver
+	}
+	for mac, driver := range s.GetHvacs() {
+		drivers[mac] = driver
+	}
+	for mac, driver := range s.GetNanos() {
+		drivers[mac] = driver
+	}
+	for mac, driver := range drivers {
+		if rule.Mac != "" && rule.Mac != mac {
+			continue
+		}
+		target := driverAlarmTarget(mac, driver)
+		if rule.Group != nil && (target.group == nil || *target.group != *rule.Group) {
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+//alarmConditions check if all the rule conditions are fulfilled, a missing measure fails the condition
+func alarmConditions(rule database.AlarmRule, values map[string]float64, active bool) bool {
+	for _, cond := range rule.Conditions {
+		value, ok := values[cond.Metric]
+		if !ok || !cond.Matches(value, active) {
+			return false
+		}
+	}
+	return true
+}
+
+//conditionValues return the measures of the rule metrics
+func conditionValues(rule database.AlarmRule, values map[string]float64) map[string]float64 {
+	res := make(map[string]float64)
+	for _, cond := range rule.Conditions {
+		if value, ok := values[cond.Metric]; ok {
+			res[cond.Metric] = value
+		}
+	}
+	return res
+}
+
+//publishAlarm forward a raised or cleared alarm to the server and the API clients
+func (s *Service) publishAlarm(alarm database.Alarm) {
+	target := alarm.Mac
+	if target == "" && alarm.Group != nil {
+		target = "group " + strconv.Itoa(*alarm.Group)
+	}
+	if alarm.Active {
+		rlog.Warn("Alarm " + alarm.Rule + " (" + alarm.Severity + ") raised on " + target)
+	} else {
+		rlog.Info("Alarm " + alarm.Rule + " cleared on " + target)
+	}
+	dump, _ := json.Marshal(alarm)
+	s.serverQueueCommand("/read/switch/"+s.mac+"/"+UrlAlarm, string(dump))
+	if s.api != nil {
+		s.api.SendAlarmEvent(alarm.Group, alarm)
+	}
+}
+
+//evaluateAlarm update the debounce state of a rule on a target
+func (s *Service) evaluateAlarm(state *alarmState, rule database.AlarmRule, target alarmTarget, now time.Time) {
+	active := state.alarm != nil
+	matched := alarmConditions(rule, target.values, active)
+	values := conditionValues(rule, target.values)
+	if active {
+		state.alarm.Values = values
+	}
+	if matched == active {
+		state.since = time.Time{}
+		return
+	}
+	if state.since.IsZero() {
+		state.since = now
+	}
+
+	elapsed := now.Sub(state.since)
+	if !active && elapsed >= time.Duration(rule.RaiseDelay)*time.Second {
+		state.alarm = &database.Alarm{
+			Rule:     rule.Name,
+			Label:    rule.Label,
+			Severity: rule.Severity,
+			Group:    target.group,
+			Mac:      target.mac,
+			Active:   true,
+			Values:   values,
+			Raised:   now.UTC().Format(time.RFC3339),
+		}
+		state.since = time.Time{}
+		s.publishAlarm(*state.alarm)
+	} else if active && elapsed >= time.Duration(rule.ClearDelay)*time.Second {
+		s.clearAlarm(state, now)
+	}
+}
+
+func (s *Service) clearAlarm(state *alarmState, now time.Time) {
+	alarm := *state.alarm
+	alarm.Active = false
+	alarm.Cleared = now.UTC().Format(time.RFC3339)
+	state.alarm = nil
+	state.since = time.Time{}
+	s.publishAlarm(alarm)
+}
+
+//checkAlarms evaluate all the rules on their groups or drivers
+func (s *Service) checkAlarms(now time.Time) {
+	rules := database.GetAlarmRules(s.db)
+	s.alarms.mutex.Lock()
+	defer s.alarms.mutex.Unlock()
+	if s.alarms.states == nil {
+		s.alarms.states = make(map[string]*alarmState)
+	}
+	seen := make(map[string]bool)
+	for name, rule := range rules {
+		for _, target := range s.alarmTargets(rule) {
+			key := name + "/" + target.key
+			seen[key] = true
+			state, ok := s.alarms.states[key]
+			if !ok {
+				state = &alarmState{}
+				s.alarms.states[key] = state
+			}
+			s.evaluateAlarm(state, rule, target, now)
+		}
+	}
+	for key, state := range s.alarms.states {
+		if seen[key] {
+			continue
+		}
+		//rule removed or group/driver no longer seen
+		if state.alarm != nil {
+			s.clearAlarm(state, now)
+		}
+		delete(s.alarms.states, key)
+	}
+}
+
+func (s *Service) cronAlarms() {
+	timer := s.clock.NewTicker(alarmsInterval)
+	for {
+		select {
+		case <-timer.C():
+			s.checkAlarms(s.clock.Now())
+		}
+	}
+}
+
+//GetAlarms return the active alarms sorted by date
+func (s *Service) GetAlarms() []database.Alarm {
+	s.alarms.mutex.Lock()
+	defer s.alarms.mutex.Unlock()
+	alarms := []database.Alarm{}
+	for _, state := range s.alarms.states {
+		if state.alarm != nil {
+			alarms = append(alarms, *state.alarm)
+		}
+	}
+	sort.Slice(alarms, func(i, j int) bool { return alarms[i].Raised < alarms[j].Raised })
+	return alarms
+}
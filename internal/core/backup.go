@@ -0,0 +1,123 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	genericNetwork "github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	cmap "github.com/orcaman/concurrent-map"
+	"github.com/romana/rlog"
+)
+
+const (
+	UrlBackup        = "backup"
+	UrlBackupRestore = "backup/restore"
+)
+
+//BackupRestoreStatus configuration restore result sent to the server
+type BackupRestoreStatus struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+//ExportConfig return the configuration archive of the switch
+func (s *Service) ExportConfig() database.ConfigBackup {
+	backup := database.ExportConfig(s.db)
+	backup.Date = s.clock.Now().UTC().Format(time.RFC3339)
+	backup.Mac = s.mac
+	return backup
+}
+
+//RestoreConfig replace the switch configuration by the archive one and reload it
+func (s *Service) RestoreConfig(backup database.ConfigBackup) error {
+	err := database.RestoreConfig(s.db, backup)
+	if err != nil {
+		rlog.Error("Cannot restore configuration: " + err.Error())
+		return err
+	}
+	rlog.Info("Configuration of " + backup.Mac + " saved on " + backup.Date + " restored")
+	event := make(map[string]SwitchSetup)
+	event[EventServerRestore] = SwitchSetup{}
+	s.server.Events <- event
+	return nil
+}
+
+//reloadConfiguration restart the groups, the clusters and the WAGOs from the restored configuration
+func (s *Service) reloadConfiguration() {
+	for grID, group := range s.groups {
+		s.stopGroup(group.Runtime)
+		delete(s.groups, grID)
+		s.groupStatus.Remove(strconv.Itoa(grID))
+		s.groupsFirstDay.Remove(strconv.Itoa(grID))
+	}
+	for mac, cl := range s.cluster {
+		cl.Iface.Disconnect()
+		delete(s.cluster, mac)
+	}
+
+	s.sensorWeights = cmap.New()
+
+	sw := database.GetSwitchConfig(s.db)
+	s.isConfigured = true
+	s.friendlyName = sw.FriendlyName
+	if sw.Label != nil {
+		s.label = *sw.Label
+	}
+	s.profil = sw.Profil
+	s.clusterID = sw.Cluster
+
+	groups := database.GetGroupsConfig(s.db)
+	for grID, group := range groups {
+		rlog.Info("Restore group ", grID)
+		s.createGroup(group)
+	}
+	s.expectedLeds = len(database.GetLedsConfig(s.db))
+	s.connectClusters()
+	s.sendWagosSetup()
+
+	if sw.IP != "" && sw.IP != "0" && sw.IP != s.ip {
+		//take over the IP address of the replaced switch
+		rlog.Info("Change IP configuration to " + sw.IP)
+		err := s.sys.SetIPConfig(sw.IP)
+		if err != nil {
+			rlog.Error("Cannot write IP configuration " + err.Error())
+			return
+		}
+		s.clock.Sleep(5 * time.Second)
+		rlog.Info("Restart Switch")
+		err = s.sys.Reboot()
+		if err != nil {
+			rlog.Error("Reboot finished with " + err.Error())
+		}
+	}
+}
+
+func (s *Service) onBackupExport(client genericNetwork.Client, msg genericNetwork.Message) {
+	rlog.Debug(msg.Topic() + " : " + string(msg.Payload()))
+	dump, err := json.Marshal(s.ExportConfig())
+	if err != nil {
+		rlog.Error("Cannot dump configuration ", err.Error())
+		return
+	}
+	s.serverSendCommand("/read/switch/"+s.mac+"/"+UrlBackup, string(dump))
+}
+
+func (s *Service) onBackupRestore(client genericNetwork.Client, msg genericNetwork.Message) {
+	payload := msg.Payload()
+	rlog.Debug(msg.Topic() + " : " + string(payload))
+	status := BackupRestoreStatus{}
+	var backup database.ConfigBackup
+	err := json.Unmarshal(payload, &backup)
+	if err == nil {
+		err = s.RestoreConfig(backup)
+	}
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Success = true
+	}
+	dump, _ := json.Marshal(status)
+	s.serverSendCommand("/read/switch/"+s.mac+"/"+UrlBackupRestore, string(dump))
+}
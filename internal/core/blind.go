@@ -4,11 +4,11 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/energieip/common-components-go/pkg/dblind"
 	"github.com/energieip/common-components-go/pkg/network"
 	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/energieip/swh200-firmware-go/internal/api"
 	"github.com/energieip/swh200-firmware-go/internal/database"
 	"github.com/romana/rlog"
 )
@@ -84,7 +84,17 @@ func (s *Service) sendBlindReset(mac string) {
 	s.sendBlindUpdate(driver)
 }
 
-func (s *Service) sendBlindGroupSetpoint(mac string, blind *int, slat *int) {
+//blindChannels return the blind channels of the group, empty when none is set
+func (group *Group) blindChannels() database.GroupBlindChannels {
+	cfg, _ := group.settings(database.BlindChannelSettings).(*database.GroupBlindChannels)
+	if cfg == nil || cfg.Channels == nil {
+		return *database.BlindChannelSettings.New(group.Runtime.Group).(*database.GroupBlindChannels)
+	}
+	return *cfg
+}
+
+//sendBlindGroupSetpoint send the setpoints to one channel of the blind or to both with database.BlindChannelBoth
+func (s *Service) sendBlindGroupSetpoint(mac string, channel int, blind *int, slat *int) {
 	_, ok := s.blinds.Get(mac)
 	if !ok {
 		rlog.Warn("Blind " + mac + " not plugged to this switch")
@@ -93,12 +103,12 @@ func (s *Service) sendBlindGroupSetpoint(mac string, blind *int, slat *int) {
 	conf := dblind.BlindConf{
 		Mac: mac,
 	}
-	if blind != nil {
+	if channel != database.BlindChannel2 {
 		conf.Blind1 = blind
-		conf.Blind2 = blind
-	}
-	if slat != nil {
 		conf.Slat1 = slat
+	}
+	if channel != database.BlindChannel1 {
+		conf.Blind2 = blind
 		conf.Slat2 = slat
 	}
 	s.sendBlindUpdate(conf)
@@ -108,6 +118,7 @@ func (s *Service) removeBlind(mac string) {
 	criteria := make(map[string]interface{})
 	criteria["Mac"] = mac
 	s.db.DeleteRecord(pconst.DbConfig, pconst.TbBlinds, criteria)
+	s.journalDriverRemoved(DriverTypeBlind, mac)
 	_, ok := s.blinds.Get(mac)
 	if !ok {
 		return
@@ -167,7 +178,7 @@ func (s *Service) onBlindHello(client network.Client, msg network.Message) {
 		return
 	}
 	driver.Mac = strings.ToUpper(driver.Mac)
-	s.driversSeen.Set(driver.Mac, time.Now().UTC())
+	s.driversSeen.Set(driver.Mac, s.clock.Now().UTC())
 	if driver.DumpFrequency == 0 {
 		driver.DumpFrequency = 1000 //ms default value for hello
 	}
@@ -179,6 +190,7 @@ func (s *Service) onBlindHello(client network.Client, msg network.Message) {
 		rlog.Error("Error during database update ", err.Error())
 		return
 	}
+	s.sendDriverEvent(api.EventDriverHello, DriverTypeBlind, driver.Mac, &driver.Group, driver)
 	rlog.Debug("New Blind driver stored on database " + driver.Mac)
 
 	cfg := database.GetConfigBlind(s.db, driver.Mac)
@@ -196,6 +208,26 @@ func (s *Service) sendInvalidBlindStatus(driver dblind.Blind) {
 
 	s.clusterSendCommand(url, dump)
 	s.localSendCommand(url, dump)
+	for _, grID := range s.blindGroups(driver.Mac, driver.Group) {
+		s.localSendCommand("/read/group/"+strconv.Itoa(grID)+"/error/blind", dump)
+	}
+}
+
+//blindGroups return the other groups of the switch using a blind channel
+func (s *Service) blindGroups(mac string, grID int) []int {
+	var groups []int
+	for id, group := range s.groups {
+		if id == grID {
+			continue
+		}
+		for _, blind := range group.Runtime.Blinds {
+			if blind == mac {
+				groups = append(groups, id)
+				break
+			}
+		}
+	}
+	return groups
 }
 
 func (s *Service) onBlindStatus(client network.Client, msg network.Message) {
@@ -208,7 +240,7 @@ func (s *Service) onBlindStatus(client network.Client, msg network.Message) {
 		return
 	}
 	driver.Mac = strings.ToUpper(driver.Mac)
-	s.driversSeen.Set(driver.Mac, time.Now().UTC())
+	s.driversSeen.Set(driver.Mac, s.clock.Now().UTC())
 	driver.SwitchMac = s.mac
 	cfg := database.GetConfigBlind(s.db, driver.Mac)
 	if cfg != nil {
@@ -219,6 +251,7 @@ func (s *Service) onBlindStatus(client network.Client, msg network.Message) {
 	if err != nil {
 		rlog.Error("Error during database update ", err.Error())
 	}
+	s.sendDriverEvent(api.EventDriverStatus, DriverTypeBlind, driver.Mac, &driver.Group, driver)
 	if driver.Error == 0 {
 		url := "/read/group/" + strconv.Itoa(driver.Group) + "/events/blind"
 		evt := BlindEvent{
@@ -229,6 +262,9 @@ func (s *Service) onBlindStatus(client network.Client, msg network.Message) {
 		dump, _ := evt.ToJSON()
 		s.clusterSendCommand(url, dump)
 		s.localSendCommand(url, dump)
+		for _, grID := range s.blindGroups(driver.Mac, driver.Group) {
+			s.localSendCommand("/read/group/"+strconv.Itoa(grID)+"/events/blind", dump)
+		}
 	} else {
 		s.sendInvalidBlindStatus(driver)
 	}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+//BlindAutomation sun protection state of a group
+type BlindAutomation struct {
+	Protection string    //applied protection, empty until the first move
+	Pending    string    //protection waiting for the delay
+	Since      time.Time //start of the pending protection
+	Lowered    time.Time //date the blinds were lowered by the protection
+	Manual     bool      //automation suspended by a manual command
+	TimeToAuto int       //s before going back to the automatic mode
+}
+
+//isFacadeSunlit check if the sun is in front of the group facade, always true without switch location
+func (s *Service) isFacadeSunlit(cfg database.GroupBlindAutomation) bool {
+	if s.location == nil {
+		return true
+	}
+	azimuth, elevation := sunPosition(s.clock.Now(), s.location.Latitude, s.location.Longitude)
+	if elevation < float64(cfg.GetMinElevation()) {
+		return false
+	}
+	return math.Abs(angleDiff(azimuth, float64(cfg.Orientation))) < float64(cfg.GetAperture())
+}
+
+//blindProtection return the protection expected for the group
+func (s *Service) blindProtection(group *Group, cfg database.GroupBlindAutomation) string {
+	if !s.isFacadeSunlit(cfg) {
+		return database.BlindProtectionNone
+	}
+	current := group.BlindAuto.Protection
+	temperature, ok := group.roomTemperature()
+	if cfg.HeatTemperature != nil && ok {
+		threshold := *cfg.HeatTemperature
+		if current == database.BlindProtectionHeat {
+			threshold -= database.DefaultBlindHeatHysteresis
+		}
+		if temperature >= threshold {
+			return database.BlindProtectionHeat
+		}
+	}
+	if current == database.BlindProtectionGlare || current == database.BlindProtectionHeat {
+		if s.location != nil {
+			//the lowered blinds reduce the measured brightness: keep them down while the sun lights the facade
+			return database.BlindProtectionGlare
+		}
+		//the sun position is unknown: release when the remaining brightness is low enough
+		//or after the timeout to measure again with the blinds up
+		if group.Sensors.Count() > 0 && group.Brightness < cfg.GetGlareRelease() {
+			return database.BlindProtectionNone
+		}
+		if s.clock.Now().Sub(group.BlindAuto.Lowered) >= time.Duration(cfg.GetGlareTimeout())*time.Second {
+			return database.BlindProtectionNone
+		}
+		return database.BlindProtectionGlare
+	}
+	if group.Sensors.Count() > 0 && group.Brightness >= cfg.GetGlareBrightness() {
+		return database.BlindProtectionGlare
+	}
+	return database.BlindProtectionNone
+}
+
+func (s *Service) applyBlindProtection(group *Group, cfg database.GroupBlindAutomation, protection string) {
+	blind := BlindUp
+	var slat *int
+	switch protection {
+	case database.BlindProtectionGlare:
+		blind = BlindDown
+		slat = cfg.GlareSlat
+	case database.BlindProtectionHeat:
+		blind = BlindDown
+		slat = cfg.HeatSlat
+	}
+	lowered := group.BlindAuto.Protection == database.BlindProtectionGlare || group.BlindAuto.Protection == database.BlindProtectionHeat
+	if blind == BlindDown && !lowered {
+		group.BlindAuto.Lowered = s.clock.Now()
+	}
+	group.BlindAuto.Protection = protection
+	group.BlindAuto.Pending = ""
+	group.SetpointBlinds = &blind
+	group.SetpointSlatBlinds = slat
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : blinds protection " + protection)
+	s.setpointBlind(group, &blind, slat)
+}
+
+//blindAutomationSettings return the blind automation settings of the group or nil if none is set
+func (group *Group) blindAutomationSettings() *database.GroupBlindAutomation {
+	cfg, _ := group.settings(database.BlindAutomationSettings).(*database.GroupBlindAutomation)
+	return cfg
+}
+
+//overrideBlindAutomation suspend the blind automation after a manual command
+func (s *Service) overrideBlindAutomation(group *Group) {
+	cfg := group.blindAutomationSettings()
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	group.BlindAuto.Manual = true
+	group.BlindAuto.TimeToAuto = cfg.GetWatchdog()
+	group.BlindAuto.Pending = ""
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : blinds automation overridden")
+}
+
+//checkBlindAutomation move the group blinds for the sun protection
+func (s *Service) checkBlindAutomation(group *Group) {
+	cfg := group.blindAutomationSettings()
+	if cfg == nil || !cfg.Enabled || group.Blinds.Count() == 0 {
+		group.BlindAuto = BlindAutomation{}
+		return
+	}
+	if group.BlindAuto.Manual {
+		if cfg.GetWatchdog() == 0 {
+			return
+		}
+		if group.BlindAuto.TimeToAuto > 0 {
+			group.BlindAuto.TimeToAuto--
+			return
+		}
+		group.BlindAuto.Manual = false
+		//apply the expected protection again
+		group.BlindAuto.Protection = ""
+		rlog.Info("Switch group " + strconv.Itoa(group.Runtime.Group) + " blinds back to Automatic mode")
+	}
+
+	protection := s.blindProtection(group, *cfg)
+	if protection == group.BlindAuto.Protection {
+		group.BlindAuto.Pending = ""
+		return
+	}
+	if group.BlindAuto.Protection == "" {
+		s.applyBlindProtection(group, *cfg, protection)
+		return
+	}
+	now := s.clock.Now()
+	if protection != group.BlindAuto.Pending {
+		group.BlindAuto.Pending = protection
+		group.BlindAuto.Since = now
+		return
+	}
+	if now.Sub(group.BlindAuto.Since) >= time.Duration(cfg.GetDelay())*time.Second {
+		s.applyBlindProtection(group, *cfg, protection)
+	}
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	DefaultLongPress   = 600 //ms
+	DefaultDoublePress = 400 //ms
+	DefaultDimStep     = 10  //%
+	DefaultDimInterval = 300 //ms
+
+	//blind motor commands
+	BlindStop = 0
+	BlindUp   = 1
+	BlindDown = 2
+)
+
+type buttonState struct {
+	pressed    bool
+	long       bool //long press already triggered
+	clicks     int  //short presses waiting for the double press delay
+	longTimer  Timer
+	clickTimer Timer
+	ramp       chan bool
+}
+
+//buttonsState press state indexed by group/button
+type buttonsState struct {
+	sync.Mutex
+	states map[string]*buttonState
+}
+
+func getDuration(value *int, defaultValue int) time.Duration {
+	if value != nil {
+		return time.Duration(*value) * time.Millisecond
+	}
+	return time.Duration(defaultValue) * time.Millisecond
+}
+
+//buttonName return the button of the command (A0, A1, B0, B1) or an empty string
+func buttonName(cmd SwitchCmd) string {
+	if cmd.ButtonA != nil {
+		if *cmd.ButtonA {
+			return "A1"
+		}
+		return "A0"
+	}
+	if cmd.ButtonB != nil {
+		if *cmd.ButtonB {
+			return "B1"
+		}
+		return "B0"
+	}
+	return ""
+}
+
+//onButtonEvent detect short, long and double presses from the press/release events
+func (s *Service) onButtonEvent(cfg database.GroupButtons, button string, pressed bool) {
+	key := strconv.Itoa(cfg.Group) + "/" + button
+	s.buttons.Lock()
+	defer s.buttons.Unlock()
+	state, ok := s.buttons.states[key]
+	if !ok {
+		state = &buttonState{}
+		s.buttons.states[key] = state
+	}
+
+	if pressed {
+		if state.pressed {
+			//repeated press event
+			return
+		}
+		state.pressed = true
+		state.long = false
+		if state.clickTimer != nil {
+			state.clickTimer.Stop()
+			state.clickTimer = nil
+		}
+		state.longTimer = s.clock.AfterFunc(getDuration(cfg.LongPress, DefaultLongPress), func() {
+			s.buttons.Lock()
+			defer s.buttons.Unlock()
+			if !state.pressed || state.long {
+				return
+			}
+			state.long = true
+			state.clicks = 0
+			action := cfg.GetAction(button, database.ButtonPressLong)
+			if action == nil {
+				return
+			}
+			rlog.Info("Group " + strconv.Itoa(cfg.Group) + " button " + button + " long press: " + action.Action)
+			if action.Action == database.ButtonActionDimUp || action.Action == database.ButtonActionDimDown {
+				state.ramp = make(chan bool)
+				go s.dimRamp(cfg, *action, state.ramp)
+				return
+			}
+			go s.runButtonAction(cfg, *action)
+		})
+		return
+	}
+
+	if !state.pressed {
+		return
+	}
+	state.pressed = false
+	if state.longTimer != nil {
+		state.longTimer.Stop()
+		state.longTimer = nil
+	}
+	if state.long {
+		if state.ramp != nil {
+			close(state.ramp)
+			state.ramp = nil
+		}
+		return
+	}
+	state.clicks++
+	if state.clicks >= 2 {
+		state.clicks = 0
+		s.triggerButton(cfg, button, database.ButtonPressDouble)
+		return
+	}
+	if cfg.GetAction(button, database.ButtonPressDouble) == nil {
+		//no need to wait for a second press
+		state.clicks = 0
+		s.triggerButton(cfg, button, database.ButtonPressShort)
+		return
+	}
+	state.clickTimer = s.clock.AfterFunc(getDuration(cfg.DoublePress, DefaultDoublePress), func() {
+		s.buttons.Lock()
+		defer s.buttons.Unlock()
+		if state.pressed || state.clicks == 0 {
+			return
+		}
+		state.clicks = 0
+		s.triggerButton(cfg, button, database.ButtonPressShort)
+	})
+}
+
+func (s *Service) triggerButton(cfg database.GroupButtons, button string, press string) {
+	action := cfg.GetAction(button, press)
+	if action == nil {
+		rlog.Debug("Group " + strconv.Itoa(cfg.Group) + " no action for button " + button + " " + press + " press")
+		return
+	}
+	rlog.Info("Group " + strconv.Itoa(cfg.Group) + " button " + button + " " + press + " press: " + action.Action)
+	go s.runButtonAction(cfg, *action)
+}
+
+//getGroupLedsSetpoint return the current LED setpoint of a group
+func (s *Service) getGroupLedsSetpoint(grID int) int {
+	val, ok := s.groupStatus.Get(strconv.Itoa(grID))
+	if !ok || val == nil {
+		return 0
+	}
+	status, err := gm.ToGroupStatus(val)
+	if err != nil || status == nil {
+		return 0
+	}
+	return status.SetpointLeds
+}
+
+func (s *Service) setGroupLedsSetpoint(grID int, setpoint int) int {
+	if setpoint < 0 {
+		setpoint = 0
+	}
+	if setpoint > 100 {
+		setpoint = 100
+	}
+	auto := false
+	s.reloadGroupConfig(grID, gm.GroupConfig{
+		Group:        grID,
+		Auto:         &auto,
+		SetpointLeds: &setpoint,
+	})
+	return setpoint
+}
+
+func (s *Service) setGroupBlinds(grID int, value int) {
+	s.reloadGroupConfig(grID, gm.GroupConfig{
+		Group:          grID,
+		SetpointBlinds: &value,
+	})
+}
+
+func (s *Service) runButtonAction(cfg database.GroupButtons, action database.ButtonAction) {
+	grID := cfg.Group
+	if _, ok := s.groups[grID]; !ok {
+		return
+	}
+	step := DefaultDimStep
+	if cfg.DimStep != nil {
+		step = *cfg.DimStep
+	}
+	switch action.Action {
+	case database.ButtonActionToggle:
+		setpoint := 100
+		if s.getGroupLedsSetpoint(grID) > 0 {
+			setpoint = 0
+		}
+		s.setGroupLedsSetpoint(grID, setpoint)
+	case database.ButtonActionDimUp:
+		s.setGroupLedsSetpoint(grID, s.getGroupLedsSetpoint(grID)+step)
+	case database.ButtonActionDimDown:
+		s.setGroupLedsSetpoint(grID, s.getGroupLedsSetpoint(grID)-step)
+	case database.ButtonActionBlindsUp:
+		s.setGroupBlinds(grID, BlindUp)
+	case database.ButtonActionBlindsDown:
+		s.setGroupBlinds(grID, BlindDown)
+	case database.ButtonActionBlindsStop:
+		s.setGroupBlinds(grID, BlindStop)
+	case database.ButtonActionScene:
+		s.recallScene(grID, action.Scene)
+	case database.ButtonActionAuto:
+		auto := true
+		s.reloadGroupConfig(grID, gm.GroupConfig{
+			Group: grID,
+			Auto:  &auto,
+		})
+	}
+}
+
+//dimRamp change the LED setpoint step by step until the button release
+func (s *Service) dimRamp(cfg database.GroupButtons, action database.ButtonAction, stop chan bool) {
+	grID := cfg.Group
+	if _, ok := s.groups[grID]; !ok {
+		return
+	}
+	step := DefaultDimStep
+	if cfg.DimStep != nil {
+		step = *cfg.DimStep
+	}
+	if action.Action == database.ButtonActionDimDown {
+		step = -step
+	}
+	ticker := s.clock.NewTicker(getDuration(cfg.DimInterval, DefaultDimInterval))
+	defer ticker.Stop()
+	setpoint := s.getGroupLedsSetpoint(grID)
+	for {
+		setpoint = s.setGroupLedsSetpoint(grID, setpoint+step)
+		if setpoint == 0 || setpoint == 100 {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+//buttonSettings return the button mapping of the group or nil if none is set
+func (group *Group) buttonSettings() *database.GroupButtons {
+	cfg, _ := group.settings(database.ButtonSettings).(*database.GroupButtons)
+	return cfg
+}
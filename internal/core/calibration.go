@@ -0,0 +1,212 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	ds "github.com/energieip/common-components-go/pkg/dsensor"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	DefaultCalibrationStep   = 20 //s: LEDs slope and sensors dump
+	MinCalibrationFitQuality = 0.8
+	UrlCalibration           = "calibration"
+)
+
+var calibrationSetpoints = []int{0, 25, 50, 75, 100}
+
+//fitLinear least square fit of brightness = slope * setpoint + intercept
+//return the slope, the intercept and the coefficient of determination
+func fitLinear(points []database.CalibrationPoint) (float64, float64, float64) {
+	n := float64(len(points))
+	if n < 2 {
+		return 0, 0, 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := float64(p.Setpoint)
+		y := float64(p.Brightness)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	den := n*sumXX - sumX*sumX
+	if den == 0 {
+		return 0, 0, 0
+	}
+	slope := (n*sumXY - sumX*sumY) / den
+	intercept := (sumY - slope*sumX) / n
+
+	meanY := sumY / n
+	var ssRes, ssTot float64
+	for _, p := range points {
+		y := float64(p.Brightness)
+		estimate := slope*float64(p.Setpoint) + intercept
+		ssRes += (y - estimate) * (y - estimate)
+		ssTot += (y - meanY) * (y - meanY)
+	}
+	if ssTot == 0 {
+		return slope, intercept, 0
+	}
+	return slope, intercept, 1 - ssRes/ssTot
+}
+
+//StartGroupCalibration start the sensors calibration of a group running on the switch
+func (s *Service) StartGroupCalibration(grID int, req database.CalibrationRequest) error {
+	if _, ok := s.groups[grID]; !ok {
+		return errors.New("Group " + strconv.Itoa(grID) + " not running on this switch")
+	}
+	if _, ok := s.calibrations.Get(strconv.Itoa(grID)); ok {
+		return errors.New("Calibration already running for group " + strconv.Itoa(grID))
+	}
+	s.calibrations.Set(strconv.Itoa(grID), true)
+	go s.runCalibration(grID, req)
+	return nil
+}
+
+func (s *Service) restoreGroupMode(grID int, status gm.GroupStatus) {
+	if _, ok := s.groups[grID]; !ok {
+		return
+	}
+	auto := status.Auto
+	cfg := gm.GroupConfig{
+		Group: grID,
+		Auto:  &auto,
+	}
+	if !auto {
+		setpoint := status.SetpointLeds
+		cfg.SetpointLeds = &setpoint
+	}
+	s.reloadGroupConfig(grID, cfg)
+}
+
+func (s *Service) sendCalibrationResult(result database.GroupCalibration) {
+	err := database.SaveGroupCalibration(s.db, result)
+	if err != nil {
+		rlog.Error("Cannot save calibration of group " + strconv.Itoa(result.Group) + ": " + err.Error())
+	}
+	if result.Status == database.CalibrationRunning {
+		return
+	}
+	dump, _ := json.Marshal(result)
+	s.serverSendCommand("/read/switch/"+s.mac+"/"+UrlCalibration, string(dump))
+}
+
+//runCalibration step the group LEDs, record the sensors raw brightness and fit the correction factor
+func (s *Service) runCalibration(grID int, req database.CalibrationRequest) {
+	defer s.calibrations.Remove(strconv.Itoa(grID))
+	result := database.GroupCalibration{
+		Group:   grID,
+		Status:  database.CalibrationRunning,
+		Date:    s.clock.Now().UTC().Format(time.RFC3339),
+		Sensors: make(map[string]database.SensorCalibration),
+	}
+	status, ok := s.GetGroupsStatus()[grID]
+	if !ok || len(status.Sensors) == 0 {
+		result.Status = database.CalibrationFailed
+		result.Error = "No sensor in group " + strconv.Itoa(grID)
+		s.sendCalibrationResult(result)
+		return
+	}
+	s.sendCalibrationResult(result)
+	rlog.Info("Group " + strconv.Itoa(grID) + " start calibration")
+
+	stepDuration := time.Duration(DefaultCalibrationStep) * time.Second
+	if req.StepDuration != nil && *req.StepDuration > 0 {
+		stepDuration = time.Duration(*req.StepDuration) * time.Second
+	}
+	points := make(map[string][]database.CalibrationPoint)
+	for _, setpoint := range calibrationSetpoints {
+		if _, ok := s.groups[grID]; !ok {
+			result.Status = database.CalibrationFailed
+			result.Error = "Group " + strconv.Itoa(grID) + " removed during the calibration"
+			s.sendCalibrationResult(result)
+			return
+		}
+		s.setGroupLedsSetpoint(grID, setpoint)
+		s.clock.Sleep(stepDuration)
+		for _, mac := range status.Sensors {
+			val, ok := s.sensors.Get(mac)
+			if !ok || val == nil {
+				continue
+			}
+			sensor, err := ds.ToSensor(val)
+			if err != nil || sensor == nil || sensor.Error != 0 {
+				continue
+			}
+			points[mac] = append(points[mac], database.CalibrationPoint{
+				Setpoint:   setpoint,
+				Brightness: sensor.BrightnessRaw,
+			})
+		}
+	}
+	s.restoreGroupMode(grID, status.GroupStatus)
+
+	nbSlopes := 0
+	sumSlopes := 0.0
+	for mac, pts := range points {
+		slope, intercept, quality := fitLinear(pts)
+		result.Sensors[mac] = database.SensorCalibration{
+			Mac:        mac,
+			Points:     pts,
+			Slope:      slope,
+			Intercept:  intercept,
+			FitQuality: quality,
+		}
+		if slope > 0 {
+			nbSlopes++
+			sumSlopes += slope
+		}
+	}
+	if nbSlopes == 0 {
+		result.Status = database.CalibrationFailed
+		result.Error = "LEDs not seen by the group sensors"
+		s.sendCalibrationResult(result)
+		return
+	}
+	//by default align the sensors on the group average LED contribution
+	result.Reference = int(100 * sumSlopes / float64(nbSlopes))
+	if req.Reference != nil {
+		result.Reference = *req.Reference
+	}
+
+	fitted := 0
+	applied := 0
+	for mac, calib := range result.Sensors {
+		if calib.Slope <= 0 || len(calib.Points) < 3 || calib.FitQuality < MinCalibrationFitQuality {
+			rlog.Warn("Sensor " + mac + " calibration rejected")
+			continue
+		}
+		fitted++
+		//only the LEDs slope is corrected: the intercept is the daylight at the time of the
+		//calibration, the offset must not cancel it
+		factor := float32(float64(result.Reference) / (calib.Slope * 100))
+		calib.Factor = factor
+		calib.Applied = s.updateSensorConfig(ds.SensorConf{
+			Mac:                        mac,
+			BrightnessCorrectionFactor: &factor,
+		})
+		result.Sensors[mac] = calib
+		if !calib.Applied {
+			rlog.Warn("Sensor " + mac + " correction factor not saved")
+			continue
+		}
+		applied++
+	}
+	result.Status = database.CalibrationDone
+	if fitted == 0 {
+		result.Status = database.CalibrationFailed
+		result.Error = "Fit quality too low"
+	} else if applied == 0 {
+		result.Status = database.CalibrationFailed
+		result.Error = "Correction factors not saved"
+	}
+	rlog.Info("Group " + strconv.Itoa(grID) + " calibration " + result.Status)
+	s.sendCalibrationResult(result)
+}
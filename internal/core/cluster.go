@@ -10,11 +10,11 @@ import (
 
 //ClusterNetwork network object
 type ClusterNetwork struct {
-	Iface genericNetwork.NetworkInterface
+	Iface Broker
 }
 
 func (s *Service) createClusterNetwork() (ClusterNetwork, error) {
-	broker, err := genericNetwork.NewNetwork(genericNetwork.MQTT)
+	broker, err := s.newBroker()
 	if err != nil {
 		return ClusterNetwork{}, err
 	}
@@ -44,14 +44,9 @@ func (s *Service) remoteClusterConnection(ip string, client ClusterNetwork) erro
 			rlog.Info("Connected to cluster server broker " + ip)
 			return err
 		}
-		timer := time.NewTicker(time.Second)
 		rlog.Error("Cannot connect to broker " + ip + " error: " + err.Error())
 		rlog.Error("Try to reconnect " + ip + " in 1s")
-
-		select {
-		case <-timer.C:
-			continue
-		}
+		s.clock.Sleep(time.Second)
 	}
 }
 
@@ -67,6 +62,7 @@ func (s *Service) clusterSendCommand(topic, content string) error {
 		err := cl.Iface.SendCommand(topic, content)
 		if err != nil {
 			rlog.Error("Error : " + err.Error() + " ; " + topic + " : " + content + " cluster:  " + mac)
+			s.metrics.sendError(BrokerCluster)
 			res = err
 		} else {
 			rlog.Debug(topic + " : " + content + " cluster: " + mac)
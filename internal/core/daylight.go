@@ -0,0 +1,97 @@
+package core
+
+import (
+	"math"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+//PIController proportional-integral controller with dead band and anti-windup
+type PIController struct {
+	Kp       float64
+	Ki       float64
+	DeadBand float64
+	OutMin   float64
+	OutMax   float64
+	Integral float64
+	Output   float64
+}
+
+//Reset restart the controller from a given output (bumpless transfer)
+func (ctrl *PIController) Reset(output float64) {
+	ctrl.Integral = output
+	ctrl.Output = output
+}
+
+//Update compute the new output from the target and the measure, dt in seconds
+func (ctrl *PIController) Update(target float64, measure float64, dt float64) float64 {
+	err := target - measure
+	if math.Abs(err) <= ctrl.DeadBand {
+		//hold the output to avoid hunting around the target
+		return ctrl.Output
+	}
+	integral := ctrl.Integral + ctrl.Ki*err*dt
+	output := ctrl.Kp*err + integral
+	if output > ctrl.OutMax {
+		output = ctrl.OutMax
+		if err > 0 {
+			//anti-windup: do not integrate further in saturation
+			integral = ctrl.Integral
+		}
+	}
+	if output < ctrl.OutMin {
+		output = ctrl.OutMin
+		if err < 0 {
+			integral = ctrl.Integral
+		}
+	}
+	ctrl.Integral = math.Max(ctrl.OutMin, math.Min(ctrl.OutMax, integral))
+	ctrl.Output = output
+	return output
+}
+
+//updateBrightnessPI compute the LEDs setpoint with the PI controller
+func (s *Service) updateBrightnessPI(group *Group, cfg database.GroupDaylight) {
+	interval := 10
+	if group.Runtime.CorrectionInterval != nil {
+		interval = *group.Runtime.CorrectionInterval
+	}
+	now := s.clock.Now()
+	//real elapsed time: a presence change updates the setpoint between two corrections
+	dt := now.Sub(group.DaylightUpdated).Seconds()
+	if group.DaylightUpdated.IsZero() || dt > float64(2*interval) {
+		//the controller did not run meanwhile (empty room, first update)
+		dt = 0
+	}
+	group.DaylightUpdated = now
+	ctrl := &group.Daylight
+	ctrl.Kp = cfg.GetKp()
+	ctrl.Ki = cfg.GetKi()
+	ctrl.DeadBand = float64(cfg.GetDeadBand())
+	ctrl.OutMin = 0
+	ctrl.OutMax = 100
+	if int(math.Round(ctrl.Output)) != group.Setpoint {
+		//setpoint changed outside the controller (manual mode, empty room):
+		//nothing to integrate over the time it was not running
+		ctrl.Reset(float64(group.Setpoint))
+		dt = 0
+	}
+	output := ctrl.Update(float64(*group.Runtime.RuleBrightness), float64(group.Brightness), dt)
+	group.Setpoint = int(math.Round(output))
+	group.FirstDaySetpoint = group.Setpoint
+	if group.Runtime.FirstDayOffset != nil {
+		//first day LEDs are dimmed first
+		group.FirstDaySetpoint = group.Setpoint - *group.Runtime.FirstDayOffset
+		if group.FirstDaySetpoint < 0 {
+			group.FirstDaySetpoint = 0
+		}
+	}
+	rlog.Debugf("Group %v PI brightness %v target %v => setpoint %v", group.Runtime.Group, group.Brightness, *group.Runtime.RuleBrightness, group.Setpoint)
+}
+
+//daylightSettings return the daylight settings of the group or nil if none is set
+func (group *Group) daylightSettings() *database.GroupDaylight {
+	cfg, _ := group.settings(database.DaylightSettings).(*database.GroupDaylight)
+	return cfg
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+//room simulated brightness measured by the group sensors
+type room struct {
+	daylight float64 //in lux
+	gain     float64 //LEDs contribution in lux per %
+}
+
+func (r room) brightness(setpoint int) float64 {
+	return r.daylight + r.gain*float64(setpoint)
+}
+
+func newDaylightController() PIController {
+	return PIController{
+		Kp:       database.DefaultDaylightKp,
+		Ki:       database.DefaultDaylightKi,
+		DeadBand: database.DefaultDaylightDeadBand,
+		OutMin:   0,
+		OutMax:   100,
+	}
+}
+
+//simulate run the controller every 10s and return the measured brightness at each step
+func simulate(ctrl *PIController, r room, target float64, steps int) []float64 {
+	var measures []float64
+	setpoint := int(math.Round(ctrl.Output))
+	for i := 0; i < steps; i++ {
+		measure := r.brightness(setpoint)
+		measures = append(measures, measure)
+		setpoint = int(math.Round(ctrl.Update(target, measure, 10)))
+	}
+	return measures
+}
+
+func TestPIControllerConvergence(t *testing.T) {
+	target := 600.0
+	for _, gain := range []float64{4, 8, 15} {
+		for _, daylight := range []float64{200, 350, 500} {
+			ctrl := newDaylightController()
+			measures := simulate(&ctrl, room{daylight: daylight, gain: gain}, target, 40)
+
+			last := measures[len(measures)-1]
+			if math.Abs(target-last) > database.DefaultDaylightDeadBand+gain {
+				t.Errorf("gain %v daylight %v: brightness %v did not converge to %v", gain, daylight, last, target)
+			}
+			for i, measure := range measures {
+				if measure > target+database.DefaultDaylightDeadBand {
+					t.Errorf("gain %v daylight %v: overshoot %v at step %v", gain, daylight, measure, i)
+				}
+			}
+			for i := len(measures) - 10; i < len(measures); i++ {
+				if measures[i] != last {
+					t.Errorf("gain %v daylight %v: no steady state %v", gain, daylight, measures)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestPIControllerDeadBand(t *testing.T) {
+	ctrl := newDaylightController()
+	ctrl.Reset(40)
+	output := ctrl.Update(600, 610, 10)
+	if output != 40 || ctrl.Integral != 40 {
+		t.Errorf("output %v integral %v changed within the dead band", output, ctrl.Integral)
+	}
+}
+
+func TestPIControllerAntiWindup(t *testing.T) {
+	ctrl := newDaylightController()
+	//the LEDs cannot reach the target
+	simulate(&ctrl, room{daylight: 100, gain: 5}, 1000, 50)
+	if ctrl.Output != 100 || ctrl.Integral > 100 {
+		t.Fatalf("output %v integral %v not saturated", ctrl.Output, ctrl.Integral)
+	}
+
+	//the daylight comes back: the LEDs must dim quickly, without waiting for the integral to unwind
+	measures := simulate(&ctrl, room{daylight: 800, gain: 5}, 1000, 20)
+	steps := -1
+	for i, measure := range measures {
+		if math.Abs(1000-measure) <= database.DefaultDaylightDeadBand+5 {
+			steps = i
+			break
+		}
+	}
+	if steps < 0 || steps > 12 {
+		t.Errorf("slow recovery after saturation: %v", measures)
+	}
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func (c *testClock) NewTicker(d time.Duration) Ticker {
+	return nil
+}
+
+func (c *testClock) AfterFunc(d time.Duration, f func()) Timer {
+	return nil
+}
+
+func TestUpdateBrightnessPIElapsedTime(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := &Service{clock: clock}
+	target := 600
+	group := &Group{
+		Runtime: gm.GroupConfig{
+			Group:          1,
+			RuleBrightness: &target,
+		},
+	}
+	cfg := database.GroupDaylight{
+		Group:      1,
+		Controller: database.DaylightControllerPI,
+	}
+
+	//someone comes in: proportional step only
+	group.Brightness = 200
+	s.updateBrightnessPI(group, cfg)
+	expected := int(math.Round(database.DefaultDaylightKp * 400))
+	if group.Setpoint != expected {
+		t.Fatalf("first setpoint %v, expected %v", group.Setpoint, expected)
+	}
+
+	//next update 3s later integrates 3s, not a whole correction interval
+	clock.Sleep(3 * time.Second)
+	group.Brightness = 300
+	s.updateBrightnessPI(group, cfg)
+	integral := database.DefaultDaylightKi * 300 * 3
+	expected = int(math.Round(database.DefaultDaylightKp*300 + integral))
+	if group.Setpoint != expected {
+		t.Errorf("setpoint %v after 3s, expected %v", group.Setpoint, expected)
+	}
+
+	//the room stayed empty for an hour: nothing is integrated
+	clock.Sleep(time.Hour)
+	s.updateBrightnessPI(group, cfg)
+	expected = int(math.Round(database.DefaultDaylightKp*300 + integral))
+	if group.Setpoint != expected {
+		t.Errorf("setpoint %v after an hour, expected %v", group.Setpoint, expected)
+	}
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	energyMaxGap = 5 * time.Minute //longer intervals between two dumps are not integrated
+)
+
+//energyRetention how long the buckets of each period are kept
+var energyRetention = map[string]time.Duration{
+	database.EnergyQuarter: 2 * 24 * time.Hour,
+	database.EnergyHour:    31 * 24 * time.Hour,
+	database.EnergyDay:     2 * 366 * 24 * time.Hour,
+}
+
+type driverPower struct {
+	group    int
+	category string
+	power    int //W
+}
+
+type energyMeter struct {
+	mutex      sync.Mutex
+	lastSample time.Time
+	powers     map[string]driverPower
+	buckets    map[string]*database.EnergyBucket //current bucket per period
+}
+
+//EnergyStatus energy consumed during the current periods, sent in the server dump
+type EnergyStatus struct {
+	Quarter *database.EnergyBucket `json:"quarter,omitempty"`
+	Hour    *database.EnergyBucket `json:"hour,omitempty"`
+	Day     *database.EnergyBucket `json:"day,omitempty"`
+}
+
+//currentEnergyBucket return the bucket of the period containing the given date; the bucket
+//stored before a restart is continued
+func (s *Service) currentEnergyBucket(period string, date time.Time) *database.EnergyBucket {
+	start := database.EnergyPeriodStart(period, date).Format(time.RFC3339)
+	bucket, ok := s.energy.buckets[period]
+	if ok && bucket.Start == start {
+		return bucket
+	}
+	bucket = database.GetEnergyBucket(s.db, period, start)
+	if bucket == nil {
+		newBucket := database.NewEnergyBucket(period, date)
+		bucket = &newBucket
+	}
+	s.energy.buckets[period] = bucket
+	return bucket
+}
+
+//saveEnergyBuckets store the current buckets
+func (s *Service) saveEnergyBuckets() {
+	for _, bucket := range s.energy.buckets {
+		err := database.SaveEnergyBucket(s.db, *bucket)
+		if err != nil {
+			rlog.Error("Cannot save energy bucket " + bucket.Period + " " + bucket.Start + ": " + err.Error())
+		}
+	}
+}
+
+//purgeEnergyBuckets remove the buckets older than their retention
+func (s *Service) purgeEnergyBuckets(now time.Time) {
+	for period, retention := range energyRetention {
+		database.RemoveEnergyBuckets(s.db, period, now.Add(-retention))
+	}
+}
+
+//integrateEnergy count the energy consumed between two dates at the given powers
+func (s *Service) integrateEnergy(from, to time.Time, powers map[string]driverPower) {
+	quarter := database.EnergyPeriods[database.EnergyQuarter]
+	for from.Before(to) {
+		end := database.EnergyPeriodStart(database.EnergyQuarter, from).Add(quarter)
+		if end.After(to) {
+			end = to
+		}
+		hours := end.Sub(from).Hours()
+		for period := range database.EnergyPeriods {
+			bucket := s.currentEnergyBucket(period, from)
+			for mac, driver := range powers {
+				bucket.Add(mac, driver.group, driver.category, float64(driver.power)*hours)
+			}
+		}
+		if end.Equal(database.EnergyPeriodStart(database.EnergyQuarter, end)) {
+			//end of a quarter: store the closed quarter and the hour and day in progress
+			s.saveEnergyBuckets()
+			if end.Equal(database.EnergyPeriodStart(database.EnergyDay, end)) {
+				s.purgeEnergyBuckets(end)
+			}
+		}
+		from = end
+	}
+}
+
+//meterEnergy integrate the driver powers of the last dump period; the power of each driver
+//is held until the next dump
+func (s *Service) meterEnergy(now time.Time, powers map[string]driverPower) {
+	s.energy.mutex.Lock()
+	defer s.energy.mutex.Unlock()
+	if s.energy.buckets == nil {
+		s.energy.buckets = make(map[string]*database.EnergyBucket)
+	}
+	if !s.energy.lastSample.IsZero() {
+		elapsed := now.Sub(s.energy.lastSample)
+		if elapsed > 0 && elapsed <= energyMaxGap {
+			s.integrateEnergy(s.energy.lastSample, now, s.energy.powers)
+		} else if elapsed > energyMaxGap {
+			rlog.Warn("No power measure since " + s.energy.lastSample.Format(time.RFC3339) + ": energy not counted")
+			s.saveEnergyBuckets()
+		}
+	}
+	s.energy.lastSample = now
+	s.energy.powers = powers
+}
+
+//stopEnergy store the energy counted so far
+func (s *Service) stopEnergy() {
+	s.energy.mutex.Lock()
+	defer s.energy.mutex.Unlock()
+	s.saveEnergyBuckets()
+}
+
+//getEnergyStatus return a copy of the current buckets
+func (s *Service) getEnergyStatus() EnergyStatus {
+	s.energy.mutex.Lock()
+	defer s.energy.mutex.Unlock()
+	all := func(grID int) bool { return true }
+	status := EnergyStatus{}
+	for period, bucket := range s.energy.buckets {
+		current := bucket.Filter(all)
+		switch period {
+		case database.EnergyQuarter:
+			status.Quarter = &current
+		case database.EnergyHour:
+			status.Hour = &current
+		case database.EnergyDay:
+			status.Day = &current
+		}
+	}
+	return status
+}
+
+//GetEnergy return the buckets of a period starting between from and to, including the one in progress
+func (s *Service) GetEnergy(period string, from, to time.Time) []database.EnergyBucket {
+	s.energy.mutex.Lock()
+	defer s.energy.mutex.Unlock()
+	buckets := []database.EnergyBucket{}
+	current, ok := s.energy.buckets[period]
+	if ok {
+		start, _ := time.Parse(time.RFC3339, current.Start)
+		if start.Before(from) || start.After(to) {
+			ok = false
+		}
+	}
+	for _, bucket := range database.GetEnergyBuckets(s.db, period, from, to) {
+		if ok && bucket.Start == current.Start {
+			//the stored copy of the bucket in progress is outdated
+			continue
+		}
+		buckets = append(buckets, bucket)
+	}
+	if ok {
+		buckets = append(buckets, current.Filter(func(grID int) bool { return true }))
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start < buckets[j].Start })
+	return buckets
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	"github.com/energieip/swh200-firmware-go/internal/api"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+const (
+	DriverTypeLed    = "led"
+	DriverTypeSensor = "sensor"
+	DriverTypeBlind  = "blind"
+	DriverTypeHvac   = "hvac"
+	DriverTypeWago   = "wago"
+	DriverTypeNano   = "nano"
+)
+
+//sendDriverEvent forward a driver event to the API websocket clients
+//the lifecycle changes are reported to the server by the drivers journal
+func (s *Service) sendDriverEvent(evtType string, driverType string, mac string, grID *int, data interface{}) {
+	if evtType == api.EventDriverLost {
+		s.metrics.driverLost(driverType)
+	}
+	s.journalDriverEvent(evtType, driverType, mac, grID, data)
+	if s.api == nil {
+		return
+	}
+	s.api.SendDriverEvent(evtType, driverType, mac, grID, data)
+}
+
+//groupStatusChanged check if a relevant group information changed since the last status
+func groupStatusChanged(old gm.GroupStatus, status gm.GroupStatus) bool {
+	return old.Presence != status.Presence ||
+		old.Brightness != status.Brightness ||
+		old.SetpointLeds != status.SetpointLeds ||
+		old.SetpointLedsFirstDay != status.SetpointLedsFirstDay ||
+		old.Error != status.Error ||
+		old.Auto != status.Auto ||
+		old.WindowsOpened != status.WindowsOpened ||
+		old.HvacsTargetMode != status.HvacsTargetMode
+}
+
+func sameValue(old *int, value *int) bool {
+	if old == nil || value == nil {
+		return old == value
+	}
+	return *old == *value
+}
+
+func sameBlindsPositions(old map[string]database.BlindPosition, positions map[string]database.BlindPosition) bool {
+	dumpOld, _ := json.Marshal(old)
+	dump, _ := json.Marshal(positions)
+	return string(dumpOld) == string(dump)
+}
+
+//sendGroupStatusEvent forward the group status to the API websocket clients when it changed
+func (s *Service) sendGroupStatusEvent(status database.GroupStatus) {
+	if s.api == nil {
+		return
+	}
+	val, ok := s.groupStatus.Get(strconv.Itoa(status.Group))
+	if ok && val != nil {
+		old, err := database.ToGroupStatus(val)
+		if err == nil && old != nil && !groupStatusChanged(old.GroupStatus, status.GroupStatus) && sameBlindsPositions(old.BlindsPositions, status.BlindsPositions) &&
+			sameValue(old.ThermalDemand, status.ThermalDemand) && sameValue(old.AirQualityIndex, status.AirQualityIndex) {
+			return
+		}
+	}
+	s.api.SendGroupEvent(api.EventGroupStatus, status.Group, status)
+}
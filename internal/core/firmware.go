@@ -1,9 +1,10 @@
 package core
 
 import (
+	"encoding/json"
 	"math/rand"
 	"os"
-	"os/exec"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -26,7 +27,6 @@ import (
 	ds "github.com/energieip/common-components-go/pkg/dsensor"
 	sd "github.com/energieip/common-components-go/pkg/dswitch"
 	pkg "github.com/energieip/common-components-go/pkg/service"
-	"github.com/energieip/common-components-go/pkg/tools"
 	cmap "github.com/orcaman/concurrent-map"
 	"github.com/romana/rlog"
 )
@@ -43,6 +43,14 @@ const (
 	DefaultTimerDump = 10000
 )
 
+//SwitchDump status sent to the server with the energy consumed during the current periods
+//and the group blind channels positions
+type SwitchDump struct {
+	sd.SwitchStatus
+	Energy EnergyStatus                 `json:"energy"`
+	Groups map[int]database.GroupStatus `json:"groups"` //replace the switch status groups
+}
+
 //Service content
 type Service struct {
 	server                ServerNetwork             //Remote server
@@ -63,44 +71,73 @@ type Service struct {
 	leds                  cmap.ConcurrentMap
 	ledsToAuto            map[string]*int
 	sensors               cmap.ConcurrentMap
+	sensorWeights         cmap.ConcurrentMap //weighted rule weight by sensor MAC, read from the sensors setup
 	groups                map[int]Group
 	blinds                cmap.ConcurrentMap
 	nanos                 cmap.ConcurrentMap
 	wagos                 cmap.ConcurrentMap
 	hvacs                 cmap.ConcurrentMap
 	groupStatus           cmap.ConcurrentMap
+	groupsFirstDay        cmap.ConcurrentMap //scene first day setpoint applied in manual mode
+	buttons               buttonsState
+	calibrations          cmap.ConcurrentMap //groups under calibration
+	runtimeMaxAge         time.Duration      //maximum age of the group runtime states restored on start
+	offline               offlineQueue       //server messages waiting for the network broker
+	energy                energyMeter        //energy consumed by the drivers
+	metrics               serviceMetrics     //counters exposed on /metrics
+	journal               driversJournal     //last driver states recorded in the journal
+	alarms                alarmsEngine       //alarm rules states
+	location              *LocationConfig    //switch location for the sun position, nil when unknown
 	conf                  pkg.ServiceConfig
 	driversSeen           cmap.ConcurrentMap
 	api                   *api.API
 	consumption           *dswitch.SwitchConsumptions
 	expectedLeds          int //for BAES control
+	hw                    Hardware
+	sys                   System
+	clock                 Clock
+	newBroker             func() (Broker, error)
+	apiServer             api.HTTPServer
+	platformConf          *pkg.ServiceConfig
 }
 
 //Initialize service
 func (s *Service) Initialize(confFile string) error {
+	if s.clock == nil {
+		//not created by NewService: run on the switch
+		s.setPlatform(Platform{})
+	}
 	s.events = make(chan string)
 	s.leds = cmap.New()
 	s.ledsToAuto = make(map[string]*int)
 	s.sensors = cmap.New()
+	s.sensorWeights = cmap.New()
 	s.blinds = cmap.New()
 	s.hvacs = cmap.New()
 	s.nanos = cmap.New()
 	s.wagos = cmap.New()
 	s.groupStatus = cmap.New()
+	s.groupsFirstDay = cmap.New()
+	s.buttons.states = make(map[string]*buttonState)
+	s.calibrations = cmap.New()
 	s.groups = make(map[int]Group)
 	s.cluster = make(map[string]ClusterNetwork)
 	s.driversSeen = cmap.New()
 	conso := dswitch.SwitchConsumptions{}
 	s.consumption = &conso
 
-	conf, err := pkg.ReadServiceConfig(confFile)
-	if err != nil {
-		rlog.Error("Cannot parse configuration file " + err.Error())
-		return err
+	conf := s.platformConf
+	if conf == nil {
+		var err error
+		conf, err = pkg.ReadServiceConfig(confFile)
+		if err != nil {
+			rlog.Error("Cannot parse configuration file " + err.Error())
+			return err
+		}
 	}
 	s.conf = *conf
 
-	mac, ip := tools.GetNetworkInfo()
+	mac, ip := s.sys.NetworkInfo()
 	s.ip = ip
 	s.mac = strings.ToUpper(mac)
 	s.services = make(map[string]pkg.Service)
@@ -113,13 +150,27 @@ func (s *Service) Initialize(confFile string) error {
 
 	s.timerDump = DefaultTimerDump
 	s.friendlyName = s.mac
+	s.offline.conf = readOfflineConfig(confFile)
+	s.offline.replay = make(chan bool, 1)
+	s.offline.load(s.clock.Now())
+	s.location = readLocationConfig(confFile)
 
-	db, err := database.ConnectDatabase(conf.DB.ClientIP, conf.DB.ClientPort)
+	storage, err := database.ReadStorageConfig(confFile)
 	if err != nil {
-		rlog.Error("Cannot connect to database " + err.Error())
+		rlog.Error("Cannot parse storage configuration " + err.Error())
 		return err
 	}
-	s.db = db
+	s.runtimeMaxAge = time.Duration(*storage.RuntimeMaxAge) * time.Second
+	if s.db == nil {
+		db, err := database.ConnectDatabase(storage, conf.DB.ClientIP, conf.DB.ClientPort)
+		if err != nil {
+			rlog.Error("Cannot connect to database " + err.Error())
+			return err
+		}
+		s.db = db
+	} else {
+		database.PrepareDB(s.db)
+	}
 
 	groups := database.GetGroupsConfig(s.db)
 	for grID, group := range groups {
@@ -147,6 +198,20 @@ func (s *Service) Initialize(confFile string) error {
 		return err
 	}
 
+	s.connectClusters()
+	s.sendWagosSetup()
+
+	go s.remoteServerConnection()
+	web := api.InitAPI(s.db, *conf, s.consumption, s, s.apiServer)
+	s.api = web
+	rlog.Info("SwitchCore service started")
+	go s.activateGPIOs()
+	go s.baesManagement()
+	go s.cronCheckNetwork()
+	return nil
+}
+
+func (s *Service) connectClusters() {
 	clusters := database.GetClusterConfig(s.db)
 	for _, cl := range clusters {
 		client, err := s.createClusterNetwork()
@@ -157,7 +222,9 @@ func (s *Service) Initialize(confFile string) error {
 		s.cluster[cl.Mac] = client
 		go s.remoteClusterConnection(cl.IP, client)
 	}
+}
 
+func (s *Service) sendWagosSetup() {
 	wagos := database.GetWagosConfig(s.db)
 	for _, wago := range wagos {
 		if wago.Mac != "" {
@@ -168,30 +235,20 @@ func (s *Service) Initialize(confFile string) error {
 			}
 		}
 	}
-
-	go s.remoteServerConnection()
-	web := api.InitAPI(s.db, *conf, s.consumption)
-	s.api = web
-	rlog.Info("SwitchCore service started")
-	go s.activateGPIOs()
-	go s.baesManagement()
-	go s.cronCheckNetwork()
-	return nil
 }
 
 func (s *Service) cronCheckNetwork() {
-	timerDump := time.NewTicker(5 * time.Minute)
+	timerDump := s.clock.NewTicker(5 * time.Minute)
 	for {
 		select {
-		case <-timerDump.C:
+		case <-timerDump.C():
 			count := s.leds.Count() + s.sensors.Count() + s.blinds.Count() + s.hvacs.Count()
 			if count == 0 {
 				rlog.Info("No driver seen: reboot")
-				cmd := exec.Command("reboot")
-				cmd.Run()
+				s.sys.Reboot()
 			}
 			timerDump.Stop()
-			timerDump = time.NewTicker(5 * time.Minute)
+			timerDump = s.clock.NewTicker(5 * time.Minute)
 		}
 	}
 }
@@ -199,11 +256,10 @@ func (s *Service) cronCheckNetwork() {
 func (s *Service) baesManagement() {
 	min := (s.expectedLeds * 90) / 100
 	for {
-		time.Sleep(5 * time.Second)
+		s.clock.Sleep(5 * time.Second)
 		if s.leds.Count() >= min {
 			rlog.Info("Switch is ready switch BAES off")
-			cmd := exec.Command("gpio", "write", "43", "1")
-			_, err := cmd.CombinedOutput()
+			err := s.hw.WriteGPIO(43, 1)
 			if err != nil {
 				rlog.Error("gpio write 43 1 update finished with " + err.Error())
 				return
@@ -219,6 +275,7 @@ func (s *Service) Stop() {
 	s.localDisconnect()
 	s.serverDisconnect()
 	s.clusterDisconnect()
+	s.stopEnergy()
 	database.DBClose(s.db)
 	rlog.Info("SwitchCore service stopped")
 }
@@ -278,7 +335,7 @@ func (s *Service) sendDump() {
 	status.ClusterBroker = clusters
 
 	status.Services = services
-	timeNow := time.Now().UTC()
+	timeNow := s.clock.Now().UTC()
 
 	dumpSensors := make(map[string]ds.Sensor)
 	dumpLeds := make(map[string]dl.Led)
@@ -287,6 +344,7 @@ func (s *Service) sendDump() {
 	dumpWagos := make(map[string]dwago.Wago)
 	dumpNanos := make(map[string]dnanosense.Nanosense)
 	dumpGroups := make(map[int]dgroup.GroupStatus)
+	powers := make(map[string]driverPower)
 	for _, dr := range s.leds.Items() {
 		driver, err := dl.ToLed(dr)
 		if err != nil {
@@ -297,11 +355,13 @@ func (s *Service) sendDump() {
 			maxDuration := time.Duration(5*driver.DumpFrequency) * time.Millisecond
 			if timeNow.Sub(val.(time.Time)) <= maxDuration {
 				dumpLeds[driver.Mac] = *driver
+				powers[driver.Mac] = driverPower{group: driver.Group, category: database.EnergyLighting, power: driver.LinePower}
 				ledsPower += int64(driver.LinePower)
 				totalPower += int64(driver.LinePower)
 				continue
 			} else {
 				rlog.Warn("LED " + driver.Mac + " no longer seen; drop it")
+				s.sendDriverEvent(api.EventDriverLost, DriverTypeLed, driver.Mac, &driver.Group, driver)
 				s.leds.Remove(driver.Mac)
 				s.driversSeen.Remove(driver.Mac)
 				_, ok := s.ledsToAuto[driver.Mac]
@@ -331,6 +391,7 @@ func (s *Service) sendDump() {
 				continue
 			} else {
 				rlog.Warn("Sensor " + driver.Mac + " no longer seen; drop it")
+				s.sendDriverEvent(api.EventDriverLost, DriverTypeSensor, driver.Mac, &driver.Group, driver)
 				s.sendInvalidStatus(*driver)
 				s.sensors.Remove(driver.Mac)
 				s.driversSeen.Remove(driver.Mac)
@@ -354,11 +415,13 @@ func (s *Service) sendDump() {
 			maxDuration := time.Duration(5*driver.DumpFrequency) * time.Millisecond
 			if timeNow.Sub(val.(time.Time)) <= maxDuration {
 				dumpBlinds[driver.Mac] = *driver
+				powers[driver.Mac] = driverPower{group: driver.Group, category: database.EnergyBlinds, power: driver.LinePower}
 				blindsPower += int64(driver.LinePower)
 				totalPower += int64(driver.LinePower)
 				continue
 			} else {
 				rlog.Warn("Blind " + driver.Mac + " no longer seen; drop it")
+				s.sendDriverEvent(api.EventDriverLost, DriverTypeBlind, driver.Mac, &driver.Group, driver)
 				s.sendInvalidBlindStatus(*driver)
 				s.blinds.Remove(driver.Mac)
 				s.driversSeen.Remove(driver.Mac)
@@ -382,11 +445,13 @@ func (s *Service) sendDump() {
 			maxDuration := time.Duration(5*driver.DumpFrequency) * time.Millisecond
 			if timeNow.Sub(val.(time.Time)) <= maxDuration {
 				dumpHvacs[driver.Mac] = *driver
+				powers[driver.Mac] = driverPower{group: driver.Group, category: database.EnergyHvacs, power: driver.LinePower}
 				hvacsPower += int64(driver.LinePower)
 				totalPower += int64(driver.LinePower)
 				continue
 			} else {
 				rlog.Warn("HVAC " + driver.Mac + " no longer seen; drop it")
+				s.sendDriverEvent(api.EventDriverLost, DriverTypeHvac, driver.Mac, &driver.Group, driver)
 				s.sendInvalidHvacStatus(*driver)
 				s.hvacs.Remove(driver.Mac)
 				s.driversSeen.Remove(driver.Mac)
@@ -413,6 +478,7 @@ func (s *Service) sendDump() {
 				continue
 			} else {
 				rlog.Warn("WAGO " + driver.Mac + " no longer seen; drop it")
+				s.sendDriverEvent(api.EventDriverLost, DriverTypeWago, driver.Mac, nil, driver)
 				s.sendInvalidWagoStatus(*driver)
 				s.wagos.Remove(driver.Mac)
 				s.driversSeen.Remove(driver.Mac)
@@ -439,6 +505,7 @@ func (s *Service) sendDump() {
 				continue
 			} else {
 				rlog.Warn("Nanos " + driver.Mac + " no longer seen; drop it")
+				s.sendDriverEvent(api.EventDriverLost, DriverTypeNano, driver.Mac, &driver.Group, driver)
 				s.sendInvalidNanoStatus(*driver)
 				s.nanos.Remove(driver.Mac)
 				s.driversSeen.Remove(driver.Mac)
@@ -500,12 +567,17 @@ func (s *Service) sendDump() {
 	s.consumption.LightingPower = int(ledsPower)
 	s.consumption.TotalPower = int(totalPower)
 	s.consumption.HvacPower = int(hvacsPower)
-
-	dump, _ := status.ToJSON()
-	s.serverSendCommand("/read/switch/"+s.mac+"/"+UrlStatus, dump)
+	s.meterEnergy(timeNow, powers)
+
+	dump, _ := json.Marshal(SwitchDump{
+		SwitchStatus: status,
+		Energy:       s.getEnergyStatus(),
+		Groups:       s.GetGroupsStatus(),
+	})
+	s.serverQueueCommand("/read/switch/"+s.mac+"/"+UrlStatus, string(dump))
 }
 
-func (s *Service) updateConfiguration(switchConfig sd.SwitchConfig) {
+func (s *Service) updateConfiguration(switchConfig SwitchSetup) {
 	if switchConfig.DumpFrequency == 0 {
 		switchConfig.DumpFrequency = DefaultTimerDump
 	}
@@ -629,7 +701,12 @@ func (s *Service) updateConfiguration(switchConfig sd.SwitchConfig) {
 	}
 
 	for _, user := range switchConfig.Users {
+		previous := database.GetUser(s.db, user.UserHash)
 		database.SaveUserConfig(s.db, user)
+		if previous != nil && !reflect.DeepEqual(previous.AccessGroups, user.AccessGroups) && s.api != nil {
+			//the tokens hold the former access groups
+			s.api.RevokeUserTokens(user.UserHash)
+		}
 	}
 
 	for grID, group := range switchConfig.Groups {
@@ -676,6 +753,11 @@ func (s *Service) removeConfiguration(switchConfig sd.SwitchConfig) {
 		if group, ok := s.groups[grID]; ok {
 			s.deleteGroup(group.Runtime)
 		}
+		database.RemoveGroupScenes(s.db, grID)
+		database.RemoveGroupCalibration(s.db, grID)
+		for _, table := range database.GroupSettingsTables() {
+			database.RemoveGroupSettings(s.db, table, grID)
+		}
 	}
 
 	for ledMac := range switchConfig.LedsConfig {
@@ -704,21 +786,24 @@ func (s *Service) removeConfiguration(switchConfig sd.SwitchConfig) {
 
 	for user := range switchConfig.Users {
 		database.RemoveUserConfig(s.db, user)
+		if s.api != nil {
+			s.api.RevokeUserTokens(user)
+		}
 	}
 }
 
 func (s *Service) cronDump() {
-	timerDump := time.NewTicker(s.timerDump * time.Millisecond)
+	timerDump := s.clock.NewTicker(s.timerDump * time.Millisecond)
 	for {
 		select {
-		case <-timerDump.C:
+		case <-timerDump.C():
 			if s.isConfigured {
 				s.sendDump()
 			} else {
 				s.sendHello()
 			}
 			timerDump.Stop()
-			timerDump = time.NewTicker(s.timerDump * time.Millisecond)
+			timerDump = s.clock.NewTicker(s.timerDump * time.Millisecond)
 		}
 	}
 }
@@ -751,17 +836,21 @@ func (s *Service) packagesRemove(switchConfig sd.SwitchConfig) {
 }
 
 func (s *Service) systemUpdate(switchConfig sd.SwitchConfig) {
-	SystemUpgrade()
+	s.sys.Upgrade()
 }
 
 //Run service mainloop
 func (s *Service) Run() error {
-	rand.Seed(time.Now().UTC().UnixNano())
+	rand.Seed(s.clock.Now().UTC().UnixNano())
 	delay := rand.Int63n(50)
-	time.Sleep(time.Duration(delay) * time.Second)
+	s.clock.Sleep(time.Duration(delay) * time.Second)
 	s.sendHello()
 	go s.cronDump()
 	go s.cronLedMode()
+	go s.cronSchedules()
+	go s.cronOfflineReplay()
+	go s.cronJournal()
+	go s.cronAlarms()
 	for {
 		select {
 		case serverEvents := <-s.server.Events:
@@ -782,6 +871,7 @@ func (s *Service) Run() error {
 							s.leds = cmap.New()
 							s.ledsToAuto = make(map[string]*int)
 							s.sensors = cmap.New()
+							s.sensorWeights = cmap.New()
 							s.blinds = cmap.New()
 							s.hvacs = cmap.New()
 							s.nanos = cmap.New()
@@ -789,6 +879,7 @@ func (s *Service) Run() error {
 							s.clusterID = 0
 							s.driversSeen = cmap.New()
 							s.groups = make(map[int]Group)
+							s.groupsFirstDay = cmap.New()
 							for mac := range s.cluster {
 								s.removeClusterMember(mac)
 							}
@@ -835,7 +926,10 @@ func (s *Service) Run() error {
 						continue
 					}
 					// s.packagesRemove(event)
-					s.removeConfiguration(event)
+					s.removeConfiguration(event.SwitchConfig)
+
+				case EventServerRestore:
+					s.reloadConfiguration()
 				}
 			}
 		}
@@ -11,26 +11,23 @@ import (
 
 func (s *Service) activateGPIOs() {
 	rlog.Info("Activate KSZ Switch")
-	cmd := exec.Command("gpio", "write", "44", "1")
-	_, err := cmd.CombinedOutput()
+	err := s.hw.WriteGPIO(44, 1)
 	if err != nil {
 		rlog.Error("gpio write 44 1 update finished with " + err.Error())
 		return
 	}
 
-	time.Sleep(10 * time.Second)
+	s.clock.Sleep(10 * time.Second)
 	rlog.Info("Activate PSE 1 Switch")
-	cmd = exec.Command("gpio", "write", "7", "1")
-	_, err = cmd.CombinedOutput()
+	err = s.hw.WriteGPIO(7, 1)
 	if err != nil {
 		rlog.Error("gpio write 7 1 update finished with " + err.Error())
 		return
 	}
-	time.Sleep(40 * time.Second)
+	s.clock.Sleep(40 * time.Second)
 
 	rlog.Info("Activate PSE 2 Switch")
-	cmd = exec.Command("gpio", "write", "1", "1")
-	_, err = cmd.CombinedOutput()
+	err = s.hw.WriteGPIO(1, 1)
 	if err != nil {
 		rlog.Error("gpio write 1 1 update finished with " + err.Error())
 		return
@@ -39,33 +36,29 @@ func (s *Service) activateGPIOs() {
 
 func (s *Service) resetPSE() {
 	rlog.Info("Down PSE 1 Switch")
-	cmd := exec.Command("gpio", "write", "7", "0")
-	_, err := cmd.CombinedOutput()
+	err := s.hw.WriteGPIO(7, 0)
 	if err != nil {
 		rlog.Error("gpio write 7 1 update finished with " + err.Error())
 		return
 	}
 
 	rlog.Info("Down PSE 2 Switch")
-	cmd = exec.Command("gpio", "write", "1", "0")
-	_, err = cmd.CombinedOutput()
+	err = s.hw.WriteGPIO(1, 0)
 	if err != nil {
 		rlog.Error("gpio write 1 1 update finished with " + err.Error())
 		return
 	}
 
 	rlog.Info("Activate PSE 1 Switch")
-	cmd = exec.Command("gpio", "write", "7", "1")
-	_, err = cmd.CombinedOutput()
+	err = s.hw.WriteGPIO(7, 1)
 	if err != nil {
 		rlog.Error("gpio write 7 1 update finished with " + err.Error())
 		return
 	}
-	time.Sleep(20 * time.Second)
+	s.clock.Sleep(20 * time.Second)
 
 	rlog.Info("Activate PSE 2 Switch")
-	cmd = exec.Command("gpio", "write", "1", "1")
-	_, err = cmd.CombinedOutput()
+	err = s.hw.WriteGPIO(1, 1)
 	if err != nil {
 		rlog.Error("gpio write 1 1 update finished with " + err.Error())
 		return
@@ -96,19 +89,35 @@ func (s *Service) getGPIOStates() map[int]int {
 }
 
 func (s *Service) getGPIOState(gpio int) int {
-	cmd := exec.Command("gpio", "read", strconv.Itoa(gpio))
-	res, err := cmd.CombinedOutput()
+	val, err := s.hw.ReadGPIO(gpio)
 	if err != nil {
 		rlog.Error("gpio read " + strconv.Itoa(gpio) + " finished with " + err.Error())
 		return 0
 	}
+	return val
+}
+
+//gpioHardware GPIOs driven by the wiringPi gpio command
+type gpioHardware struct{}
+
+func (hw gpioHardware) WriteGPIO(gpio int, value int) error {
+	cmd := exec.Command("gpio", "write", strconv.Itoa(gpio), strconv.Itoa(value))
+	_, err := cmd.CombinedOutput()
+	return err
+}
+
+func (hw gpioHardware) ReadGPIO(gpio int) (int, error) {
+	cmd := exec.Command("gpio", "read", strconv.Itoa(gpio))
+	res, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, err
+	}
 	result := strings.Trim(string(res), "\r")
 	result = strings.Trim(result, "\n")
 
 	val, err := strconv.Atoi(result)
 	if err != nil {
-		rlog.Error("Conversion issue " + err.Error())
-		return 0
+		return 0, err
 	}
-	return val
+	return val, nil
 }
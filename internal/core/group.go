@@ -2,7 +2,7 @@ package core
 
 import (
 	"encoding/json"
-	"math"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +13,7 @@ import (
 	"github.com/energieip/common-components-go/pkg/dgroup"
 	gm "github.com/energieip/common-components-go/pkg/dgroup"
 	"github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/database"
 	cmap "github.com/orcaman/concurrent-map"
 	"github.com/romana/rlog"
 )
@@ -22,6 +23,8 @@ const (
 	EventStop         = "stop"
 	EventManual       = "manual"
 	EventBlind        = "blind"
+	EventBlind1       = "blind1" //blind channel 1 command
+	EventBlind2       = "blind2" //blind channel 2 command
 	EventHvac         = "hvac"
 	EventWago         = "wago"
 	EventHvacConfig   = "hvacConfig"
@@ -75,6 +78,16 @@ type Group struct {
 	HvacsDamper        int
 	HvacsHeatCool      int
 	HvacsShift         int
+	Daylight           PIController    //daylight harvesting PI controller state
+	DaylightUpdated    time.Time       //date of the last PI controller update
+	Interlock          WindowInterlock //window opened HVAC protection state
+	Occupancy          HvacOccupancy   //presence driven HVAC state
+	BlindAuto          BlindAutomation //sun protection state
+	Thermal            ThermalControl  //switch side temperature controller state
+	AirQuality         AirQuality      //air quality ventilation state
+	RuntimeSaved       *database.GroupRuntimeState
+	RuntimeSavedDate   time.Time
+	Settings           cmap.ConcurrentMap //per group settings by configuration table
 }
 
 func (s *Service) onGroupsWagoEvent(client network.Client, msg network.Message) {
@@ -359,6 +372,12 @@ func (s *Service) dumpGroupStatus(group Group) error {
 	if group.Runtime.HvacsTargetMode != nil {
 		targetMode = *group.Runtime.HvacsTargetMode
 	}
+	if group.Occupancy.Mode != nil {
+		targetMode = *group.Occupancy.Mode
+	}
+	if group.isInterlocked() {
+		targetMode = group.Interlock.Mode
+	}
 
 	status := gm.GroupStatus{
 		Group:                   group.Runtime.Group,
@@ -408,12 +427,47 @@ func (s *Service) dumpGroupStatus(group Group) error {
 		HvacsHeatCool:           group.HvacsHeatCool,
 	}
 
-	s.groupStatus.Set(strconv.Itoa(status.Group), status)
+	full := database.GroupStatus{
+		GroupStatus:     status,
+		BlindsPositions: s.groupBlindsPositions(&group),
+		ThermalDemand:   group.Thermal.Demand,
+		AirQualityIndex: group.AirQuality.Index,
+	}
+	s.sendGroupStatusEvent(full)
+	s.groupStatus.Set(strconv.Itoa(status.Group), full)
 	return nil
 }
 
+//groupBlindsPositions return the positions reported by the group blind channels
+func (s *Service) groupBlindsPositions(group *Group) map[string]database.BlindPosition {
+	if len(group.Runtime.Blinds) == 0 {
+		return nil
+	}
+	channels := group.blindChannels()
+	positions := make(map[string]database.BlindPosition)
+	for _, mac := range group.Runtime.Blinds {
+		driver, ok := s.blinds.Get(mac)
+		if !ok || driver == nil {
+			continue
+		}
+		var position database.BlindPosition
+		inrec, _ := json.Marshal(driver)
+		json.Unmarshal(inrec, &position)
+		switch channels.Channel(mac) {
+		case database.BlindChannel1:
+			position.Blind2 = nil
+			position.Slat2 = nil
+		case database.BlindChannel2:
+			position.Blind1 = nil
+			position.Slat1 = nil
+		}
+		positions[mac] = position
+	}
+	return positions
+}
+
 func (s *Service) groupRun(group *Group) error {
-	ticker := time.NewTicker(time.Second)
+	ticker := s.clock.NewTicker(time.Second)
 	go func() {
 		group.Counter = 0
 		for {
@@ -439,8 +493,18 @@ func (s *Service) groupRun(group *Group) error {
 
 					case EventBlind:
 						rlog.Info("Received blind event ", group)
+						s.overrideBlindAutomation(group)
 						s.setpointBlind(group, group.SetpointBlinds, group.SetpointSlatBlinds)
 
+					case EventBlind1, EventBlind2:
+						channel := database.BlindChannel1
+						if eventType == EventBlind2 {
+							channel = database.BlindChannel2
+						}
+						rlog.Info("Received blind channel "+strconv.Itoa(channel)+" event ", group)
+						s.overrideBlindAutomation(group)
+						s.setpointBlindChannel(group, channel, e.SetpointBlinds, e.SetpointSlatBlinds)
+
 					case EventHvac:
 						rlog.Info("Received HVAC event ", group)
 						s.setpointHvac(group, group.ShiftTemp)
@@ -451,16 +515,21 @@ func (s *Service) groupRun(group *Group) error {
 
 					case EventHvacConfig:
 						// rlog.Info("Received HVAC Config event ", group)
-						s.setpointHvacConfig(group, e)
+						if e != nil && e.HvacsTargetMode != nil {
+							s.overrideHvacOccupancy(group, e.HvacsTargetMode)
+						}
+						s.setpointHvacConfig(group, s.interlockHvacConfig(group, e))
 
 					case EventResetDrivers:
 						rlog.Info("Received Reset EIP drivers ", group)
 						s.resetEipDrivers(group)
 					}
 				}
-			case <-ticker.C:
+			case <-ticker.C():
+				tickStart := s.clock.Now()
 				group.Counter++
-				if s.isManualMode(group) {
+				if s.isManualMode(group) && !s.calibrations.Has(strconv.Itoa(group.Runtime.Group)) {
+					//the calibration holds the LEDs setpoints until it is over
 					if group.Sensors.Count() > 0 {
 						// compute TimeToAuto and switch back to Auto mode
 						if group.Runtime.Watchdog != nil {
@@ -483,10 +552,15 @@ func (s *Service) groupRun(group *Group) error {
 				//force to compute presence to be sure that the status is consistent even if the group was is manual mode
 				s.computePresence(group)
 				s.computeOpen(group)
+				s.checkWindowInterlock(group)
+				s.checkHvacOccupancy(group)
 				s.computeSensorTemperatureAndHumidity(group)
 				s.computeBrightness(group)
 				s.computeNanosenseInfo(group)
 				s.computeHvacInfo(group)
+				s.checkBlindAutomation(group)
+				s.checkAirQuality(group)
+				s.checkThermalControl(group)
 				interval := 10
 				if group.Runtime.CorrectionInterval != nil {
 					interval = *group.Runtime.CorrectionInterval
@@ -536,6 +610,8 @@ func (s *Service) groupRun(group *Group) error {
 				if err != nil {
 					rlog.Errorf("Cannot dump status to database for " + strconv.Itoa(group.Runtime.Group) + " err " + err.Error())
 				}
+				s.saveGroupRuntime(group)
+				s.metrics.groupTick(group.Runtime.Group, s.clock.Now().Sub(tickStart))
 			}
 		}
 	}()
@@ -565,6 +641,7 @@ func (s *Service) isPresenceDetected(group *Group) bool {
 }
 
 func (s *Service) hasWindowOpened(group *Group) bool {
+	channels := group.blindChannels()
 	for _, driver := range group.Blinds.Items() {
 		blind, _ := ToBlindEvent(driver)
 		_, ok := group.BlindsIssue.Get(blind.Mac)
@@ -572,8 +649,19 @@ func (s *Service) hasWindowOpened(group *Group) bool {
 			// do not take it to account a sensor with an issue
 			continue
 		}
-		if blind.WindowStatus1 || blind.WindowStatus2 {
-			return true
+		switch channels.Channel(blind.Mac) {
+		case database.BlindChannel1:
+			if blind.WindowStatus1 {
+				return true
+			}
+		case database.BlindChannel2:
+			if blind.WindowStatus2 {
+				return true
+			}
+		default:
+			if blind.WindowStatus1 || blind.WindowStatus2 {
+				return true
+			}
 		}
 	}
 	return false
@@ -622,6 +710,11 @@ func (s *Service) computePresence(group *Group) {
 
 func (s *Service) updateBrightness(group *Group) {
 	if group.Runtime.RuleBrightness != nil {
+		daylight := group.daylightSettings()
+		if daylight != nil && daylight.Controller == database.DaylightControllerPI {
+			s.updateBrightnessPI(group, *daylight)
+			return
+		}
 		readBrightness := *group.Runtime.RuleBrightness
 		if group.Brightness > readBrightness {
 			//decrease light
@@ -655,387 +748,150 @@ func (s *Service) updateBrightness(group *Group) {
 
 func (s *Service) computeBrightness(group *Group) {
 	//compute sensor values
-	refMac := ""
-	bright := 0
-	for _, driver := range group.Sensors.Items() {
-		sensor, _ := ToSensorEvent(driver)
-		_, ok := group.SensorsIssue.Get(sensor.Mac)
+	brightness := make(map[string]float64)
+	for mac, driver := range group.Sensors.Items() {
+		_, ok := group.SensorsIssue.Get(mac)
 		if ok {
 			// do not take it to account a sensor with an issue
 			continue
 		}
-		refMac = sensor.Mac
-		bright = sensor.Brightness
-		break
-	}
-	if refMac == "" {
-		//No sensors in this group
-		return
+		sensor, _ := ToSensorEvent(driver)
+		brightness[mac] = float64(sensor.Brightness)
 	}
-	nbValidSensors := group.Sensors.Count() - group.SensorsIssue.Count()
-	if nbValidSensors <= 0 {
+	if len(brightness) == 0 {
 		//no valid sensor found
 		return
 	}
 
-	brightness := float64(bright) / float64(nbValidSensors)
-
-	sensorRule := gm.SensorAverage
-	if group.Runtime.SensorRule != nil {
-		sensorRule = *group.Runtime.SensorRule
-	}
-
-	for mac, driver := range group.Sensors.Items() {
-		if mac == refMac {
-			continue
-		}
-		sensor, _ := ToSensorEvent(driver)
-
-		_, ok := group.SensorsIssue.Get(sensor.Mac)
-		if ok {
-			// do not take it to account a sensor with an issue
-			continue
-		}
-
-		switch sensorRule {
-		case gm.SensorAverage:
-			brightness += float64(sensor.Brightness) / float64(nbValidSensors)
-		case gm.SensorMax:
-			if group.Brightness < sensor.Brightness {
-				group.Brightness = sensor.Brightness
-			}
-		case gm.SensorMin:
-			if group.Brightness > sensor.Brightness {
-				group.Brightness = sensor.Brightness
-			}
-		}
-	}
-	if sensorRule == gm.SensorAverage {
-		group.Brightness = int(math.Round(brightness))
-	}
+	rules := group.sensorRules()
+	group.Brightness = aggregateValues(brightness, sensorRule(group, rules, database.SensorMetricBrightness), rules, s.sensorWeight)
 }
 
 func (s *Service) computeSensorTemperatureAndHumidity(group *Group) {
 	//compute sensor values
-	refMac := ""
-	hum := 0
-	temp := 0
+	temperature := make(map[string]float64)
+	humidity := make(map[string]float64)
 	for mac, driver := range group.Sensors.Items() {
-		sensor, _ := ToSensorEvent(driver)
 		_, ok := group.SensorsIssue.Get(mac)
 		if ok {
 			// do not take it to account a sensor with an issue
 			continue
 		}
-		refMac = mac
-		hum = sensor.Humidity
-		temp = sensor.Temperature
-		break
-	}
-	if refMac == "" {
-		//No sensors in this group
-		return
+		sensor, _ := ToSensorEvent(driver)
+		temperature[mac] = float64(sensor.Temperature)
+		humidity[mac] = float64(sensor.Humidity)
 	}
-	nbValidSensors := group.Sensors.Count() - group.SensorsIssue.Count()
-	if nbValidSensors <= 0 {
+	if len(temperature) == 0 {
 		//no valid sensor found
 		return
 	}
 
-	ceilingTemperature := float64(temp) / float64(nbValidSensors)
-	ceilingHumidity := float64(hum) / float64(nbValidSensors)
-
-	sensorRule := gm.SensorAverage
-	if group.Runtime.SensorRule != nil {
-		sensorRule = *group.Runtime.SensorRule
-	}
-	switch sensorRule {
-	case gm.SensorMin:
-		group.CeilingTemperature = temp
-		group.CeilingHumidity = hum
-	}
-
-	for mac, d := range group.Sensors.Items() {
-		sensor, _ := ToSensorEvent(d)
-
-		_, ok := group.SensorsIssue.Get(sensor.Mac)
-		if ok {
-			// do not take it to account a sensor with an issue
-			continue
-		}
-
-		switch sensorRule {
-		case gm.SensorAverage:
-			if mac == refMac {
-				continue
-			}
-			ceilingTemperature += float64(sensor.Temperature) / float64(nbValidSensors)
-			ceilingHumidity += float64(sensor.Humidity) / float64(nbValidSensors)
-		case gm.SensorMax:
-			if group.CeilingHumidity < sensor.Humidity {
-				group.CeilingHumidity = sensor.Humidity
-			}
-			if group.CeilingTemperature < sensor.Temperature {
-				group.CeilingTemperature = sensor.Temperature
-			}
-		case gm.SensorMin:
-			if group.CeilingHumidity > sensor.Humidity {
-				group.CeilingHumidity = sensor.Humidity
-			}
-			if group.CeilingTemperature > sensor.Temperature {
-				group.CeilingTemperature = sensor.Temperature
-			}
-		}
-	}
-	if sensorRule == gm.SensorAverage {
-		group.CeilingTemperature = int(math.Round(ceilingTemperature))
-		group.CeilingHumidity = int(math.Round(ceilingHumidity))
-	}
+	rules := group.sensorRules()
+	rule := sensorRule(group, rules, database.SensorMetricTemperature)
+	group.CeilingTemperature = aggregateValues(temperature, rule, rules, s.sensorWeight)
+	group.CeilingHumidity = aggregateValues(humidity, rule, rules, s.sensorWeight)
 }
 
 func (s *Service) computeNanosenseInfo(group *Group) {
 	//compute nanosense values
-	refMac := ""
-	hum := 0
-	temp := 0
-	co2 := 0
-	cov := 0
+	temperature := make(map[string]float64)
+	hygrometry := make(map[string]float64)
+	co2 := make(map[string]float64)
+	cov := make(map[string]float64)
 	for mac, driver := range group.Nanosenses.Items() {
-		nano, _ := ToNanoEvent(driver)
 		_, ok := group.NanosensesIssue.Get(mac)
 		if ok {
 			// do not take it to account a nanosense with an issue
 			continue
 		}
-		refMac = mac
-		hum = nano.Hygrometry
-		co2 = nano.CO2
-		cov = nano.COV
-		temp = nano.Temperature
-		break
-	}
-	if refMac == "" {
-		//No sensors in this group
-		return
+		nano, _ := ToNanoEvent(driver)
+		temperature[mac] = float64(nano.Temperature)
+		hygrometry[mac] = float64(nano.Hygrometry)
+		co2[mac] = float64(nano.CO2)
+		cov[mac] = float64(nano.COV)
 	}
-	nbValidSensors := group.Nanosenses.Count() - group.NanosensesIssue.Count()
-	if nbValidSensors <= 0 {
+	if len(temperature) == 0 {
 		//no valid sensor found
 		return
 	}
 
-	temperature := float64(temp) / float64(nbValidSensors)
-	hygrometry := float64(hum) / float64(nbValidSensors)
-	CO2 := float64(co2) / float64(nbValidSensors)
-	COV := float64(cov) / float64(nbValidSensors)
-
-	sensorRule := gm.SensorAverage
-	if group.Runtime.SensorRule != nil {
-		sensorRule = *group.Runtime.SensorRule
-	}
-	switch sensorRule {
-	case gm.SensorMin:
-		group.Temperature = temp
-		group.Hygrometry = hum
-		group.CO2 = co2
-		group.COV = cov
-	}
-
-	for mac, d := range group.Nanosenses.Items() {
-		nano, _ := ToNanoEvent(d)
-
-		_, ok := group.NanosensesIssue.Get(nano.Mac)
-		if ok {
-			// do not take it to account a nano with an issue
-			continue
-		}
+	rules := group.sensorRules()
+	rule := sensorRule(group, rules, database.SensorMetricTemperature)
+	group.Temperature = aggregateValues(temperature, rule, rules, unitWeight)
+	group.Hygrometry = aggregateValues(hygrometry, rule, rules, unitWeight)
+	rule = sensorRule(group, rules, database.SensorMetricAirQuality)
+	group.CO2 = aggregateValues(co2, rule, rules, unitWeight)
+	group.COV = aggregateValues(cov, rule, rules, unitWeight)
+}
 
-		switch sensorRule {
-		case gm.SensorAverage:
-			if mac == refMac {
-				continue
-			}
-			temperature += float64(nano.Temperature) / float64(nbValidSensors)
-			hygrometry += float64(nano.Hygrometry) / float64(nbValidSensors)
-			COV += float64(nano.COV) / float64(nbValidSensors)
-			CO2 += float64(nano.CO2) / float64(nbValidSensors)
-		case gm.SensorMax:
-			if group.Hygrometry < nano.Hygrometry {
-				group.Hygrometry = nano.Hygrometry
-			}
-			if group.Temperature < nano.Temperature {
-				group.Temperature = nano.Temperature
-			}
-			if group.CO2 < nano.CO2 {
-				group.CO2 = nano.CO2
-			}
-			if group.COV < nano.COV {
-				group.COV = nano.COV
-			}
-		case gm.SensorMin:
-			if group.Hygrometry > nano.Hygrometry {
-				group.Hygrometry = nano.Hygrometry
-			}
-			if group.Temperature > nano.Temperature {
-				group.Temperature = nano.Temperature
-			}
-			if group.CO2 > nano.CO2 {
-				group.CO2 = nano.CO2
-			}
-			if group.COV > nano.COV {
-				group.COV = nano.COV
-			}
-		}
+//roomTemperature return the temperature measured by the nanosenses or by the sensors when no nanosense is valid,
+//false when none of them has a valid reading
+func (gr *Group) roomTemperature() (int, bool) {
+	if gr.Nanosenses.Count()-gr.NanosensesIssue.Count() > 0 {
+		return gr.Temperature, true
 	}
-	if sensorRule == gm.SensorAverage {
-		group.Temperature = int(math.Round(temperature))
-		group.Hygrometry = int(math.Round(hygrometry))
-		group.COV = int(math.Round(COV))
-		group.CO2 = int(math.Round(CO2))
+	if gr.Sensors.Count()-gr.SensorsIssue.Count() > 0 {
+		return gr.CeilingTemperature, true
 	}
+	return 0, false
 }
 
 func (s *Service) computeHvacInfo(group *Group) {
 	//compute hvac values
 	refMac := ""
 	occMan := 0
-	occupCool := 0
-	occupHeat := 0
-	unoccupCool := 0
-	unoccupHeat := 0
-	stdbyCool := 0
-	stdbyHeat := 0
 	forcing6ways := 0
 	forcingDamper := 0
 	heatCool := 0
 	shift := 0
+	occupCool := make(map[string]float64)
+	occupHeat := make(map[string]float64)
+	unoccupCool := make(map[string]float64)
+	unoccupHeat := make(map[string]float64)
+	stdbyCool := make(map[string]float64)
+	stdbyHeat := make(map[string]float64)
 
 	for mac, driver := range group.Hvacs.Items() {
-		hvac, _ := ToHvacEvent(driver)
 		_, ok := group.HvacsIssue.Get(mac)
 		if ok {
-			// do not take it to account a nanosense with an issue
+			// do not take it to account a hvac with an issue
 			continue
 		}
-		refMac = mac
-		occMan = hvac.OccManCmd1
-		occupCool = hvac.SetpointCoolOccupied
-		occupHeat = hvac.SetpointHeatOccupied
-		unoccupCool = hvac.SetpointCoolInoccupied
-		unoccupHeat = hvac.SetpointHeatInoccupied
-		stdbyCool = hvac.SetpointCoolStandby
-		stdbyHeat = hvac.SetpointHeatStandby
-		forcing6ways = hvac.Forcing6WaysValve
-		forcingDamper = hvac.ForcingDamper
-		heatCool = hvac.HeatCool
-		shift = hvac.Shift
-		break
+		hvac, _ := ToHvacEvent(driver)
+		if refMac == "" {
+			refMac = mac
+			occMan = hvac.OccManCmd1
+			forcing6ways = hvac.Forcing6WaysValve
+			forcingDamper = hvac.ForcingDamper
+			heatCool = hvac.HeatCool
+			shift = hvac.Shift
+		}
+		occupCool[mac] = float64(hvac.SetpointCoolOccupied)
+		occupHeat[mac] = float64(hvac.SetpointHeatOccupied)
+		unoccupCool[mac] = float64(hvac.SetpointCoolInoccupied)
+		unoccupHeat[mac] = float64(hvac.SetpointHeatInoccupied)
+		stdbyCool[mac] = float64(hvac.SetpointCoolStandby)
+		stdbyHeat[mac] = float64(hvac.SetpointHeatStandby)
 	}
 	group.Hvacs6WaysValves = forcing6ways
 	group.HvacsDamper = forcingDamper
 	group.HvacsHeatCool = heatCool
 	group.HvacsShift = shift
 	if refMac == "" {
-		//No hvac in this group
-		return
-	}
-	nbValids := group.Hvacs.Count() - group.HvacsIssue.Count()
-	if nbValids <= 0 {
-		//no valid hvac found
+		//No valid hvac in this group
 		return
 	}
 
 	group.HvacsEffectMode = occMan
 
-	grOccupCool := float64(occupCool) / float64(nbValids)
-	grOccupHeat := float64(occupHeat) / float64(nbValids)
-	grUnoccupHeat := float64(unoccupHeat) / float64(nbValids)
-	grUnoccupCool := float64(unoccupCool) / float64(nbValids)
-	grStandbyCool := float64(stdbyCool) / float64(nbValids)
-	grStandbyHeat := float64(stdbyHeat) / float64(nbValids)
-
-	sensorRule := gm.SensorAverage
-	if group.Runtime.SensorRule != nil {
-		sensorRule = *group.Runtime.SensorRule
-	}
-	switch sensorRule {
-	case gm.SensorMin:
-		group.OccupCool = occupCool
-		group.OccupHeat = occupHeat
-		group.UnoccupHeat = unoccupHeat
-		group.UnoccupCool = unoccupCool
-		group.StandbyCool = stdbyCool
-		group.StandbyHeat = stdbyHeat
-	}
-
-	for mac, d := range group.Hvacs.Items() {
-		hvac, _ := ToHvacEvent(d)
-
-		_, ok := group.HvacsIssue.Get(hvac.Mac)
-		if ok {
-			// do not take it to account a hvac with an issue
-			continue
-		}
-
-		switch sensorRule {
-		case gm.SensorAverage:
-			if mac == refMac {
-				continue
-			}
-			grOccupCool += float64(hvac.SetpointCoolOccupied) / float64(nbValids)
-			grOccupHeat += float64(hvac.SetpointHeatOccupied) / float64(nbValids)
-			grUnoccupHeat += float64(hvac.SetpointHeatInoccupied) / float64(nbValids)
-			grUnoccupCool += float64(hvac.SetpointCoolInoccupied) / float64(nbValids)
-			grStandbyCool += float64(hvac.SetpointCoolStandby) / float64(nbValids)
-			grStandbyHeat += float64(hvac.SetpointHeatStandby) / float64(nbValids)
-		case gm.SensorMax:
-			if group.OccupCool < hvac.SetpointCoolOccupied {
-				group.OccupCool = hvac.SetpointCoolOccupied
-			}
-			if group.OccupHeat < hvac.SetpointHeatOccupied {
-				group.OccupHeat = hvac.SetpointHeatOccupied
-			}
-			if group.UnoccupCool < hvac.SetpointCoolInoccupied {
-				group.UnoccupCool = hvac.SetpointCoolInoccupied
-			}
-			if group.UnoccupHeat < hvac.SetpointHeatInoccupied {
-				group.UnoccupHeat = hvac.SetpointHeatInoccupied
-			}
-			if group.StandbyCool < hvac.SetpointCoolStandby {
-				group.StandbyCool = hvac.SetpointCoolStandby
-			}
-			if group.StandbyHeat < hvac.SetpointHeatStandby {
-				group.StandbyHeat = hvac.SetpointHeatStandby
-			}
-		case gm.SensorMin:
-			if group.OccupCool > hvac.SetpointCoolOccupied {
-				group.OccupCool = hvac.SetpointCoolOccupied
-			}
-			if group.OccupHeat > hvac.SetpointHeatOccupied {
-				group.OccupHeat = hvac.SetpointHeatOccupied
-			}
-			if group.UnoccupCool > hvac.SetpointCoolInoccupied {
-				group.UnoccupCool = hvac.SetpointCoolInoccupied
-			}
-			if group.UnoccupHeat > hvac.SetpointHeatInoccupied {
-				group.UnoccupHeat = hvac.SetpointHeatInoccupied
-			}
-			if group.StandbyCool > hvac.SetpointCoolStandby {
-				group.StandbyCool = hvac.SetpointCoolStandby
-			}
-			if group.StandbyHeat > hvac.SetpointHeatStandby {
-				group.StandbyHeat = hvac.SetpointHeatStandby
-			}
-		}
-	}
-	if sensorRule == gm.SensorAverage {
-		group.OccupCool = int(math.Round(grOccupCool))
-		group.OccupHeat = int(math.Round(grOccupHeat))
-		group.UnoccupHeat = int(math.Round(grUnoccupHeat))
-		group.UnoccupCool = int(math.Round(grUnoccupCool))
-		group.StandbyCool = int(math.Round(grStandbyCool))
-		group.StandbyHeat = int(math.Round(grStandbyHeat))
-	}
+	rules := group.sensorRules()
+	rule := setpointRule(group, rules)
+	group.OccupCool = aggregateValues(occupCool, rule, rules, unitWeight)
+	group.OccupHeat = aggregateValues(occupHeat, rule, rules, unitWeight)
+	group.UnoccupCool = aggregateValues(unoccupCool, rule, rules, unitWeight)
+	group.UnoccupHeat = aggregateValues(unoccupHeat, rule, rules, unitWeight)
+	group.StandbyCool = aggregateValues(stdbyCool, rule, rules, unitWeight)
+	group.StandbyHeat = aggregateValues(stdbyHeat, rule, rules, unitWeight)
 }
 
 func (s *Service) setpointLed(group *Group) {
@@ -1074,6 +930,7 @@ func (s *Service) setpointLed(group *Group) {
 		}
 	}
 
+	manualFirstDay, hasManualFirstDay := s.groupsFirstDay.Get(strconv.Itoa(group.Runtime.Group))
 	for _, led := range group.Runtime.Leds {
 		_, ok := group.FirstDay.Get(led)
 		setpoint := group.Setpoint
@@ -1081,13 +938,31 @@ func (s *Service) setpointLed(group *Group) {
 			setpoint = group.FirstDaySetpoint
 			rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " =>  leds FirstDaySetpoint: " + strconv.Itoa(group.FirstDaySetpoint))
 		}
+		if !auto && ok && hasManualFirstDay {
+			setpoint = manualFirstDay.(int)
+			rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " =>  leds scene FirstDaySetpoint: " + strconv.Itoa(setpoint))
+		}
 		s.sendLedGroupSetpoint(led, setpoint, slopeStart, slopeStop)
 	}
 }
 
 func (s *Service) setpointBlind(group *Group, blind *int, slat *int) {
+	s.setpointBlindChannel(group, database.BlindChannelBoth, blind, slat)
+}
+
+//setpointBlindChannel send the setpoints to the group blind channels, only to the given channel unless database.BlindChannelBoth
+func (s *Service) setpointBlindChannel(group *Group, channel int, blind *int, slat *int) {
+	channels := group.blindChannels()
 	for _, driver := range group.Runtime.Blinds {
-		s.sendBlindGroupSetpoint(driver, blind, slat)
+		target := channels.Channel(driver)
+		if channel != database.BlindChannelBoth {
+			if target != database.BlindChannelBoth && target != channel {
+				//the requested channel belongs to another group
+				continue
+			}
+			target = channel
+		}
+		s.sendBlindGroupSetpoint(driver, target, blind, slat)
 	}
 }
 
@@ -1111,6 +986,11 @@ func (s *Service) setpointHvacWago(group *Group) {
 				value = dhvac.OCCUPANCY_STANDBY
 			}
 		}
+		if group.isInterlocked() {
+			//applied when the window closes
+			group.Interlock.Modes[driver] = value
+			continue
+		}
 		cfg.TargetMode = &value
 		s.updateHvacConfig(cfg)
 	}
@@ -1140,16 +1020,21 @@ func (s *Service) setpointHvacConfig(group *Group, groupCfg *gm.GroupConfig) {
 }
 
 func (s *Service) resetEipDrivers(group *Group) {
+	grID := group.Runtime.Group
+	reason := "reset requested on group " + strconv.Itoa(grID)
 	for _, driver := range group.Runtime.Blinds {
 		s.sendBlindReset(driver)
+		s.journalDriver(database.JournalReset, DriverTypeBlind, driver, &grID, reason)
 	}
 
 	for _, driver := range group.Runtime.Leds {
 		s.sendLedReset(driver)
+		s.journalDriver(database.JournalReset, DriverTypeLed, driver, &grID, reason)
 	}
 
 	for _, driver := range group.Runtime.Sensors {
 		s.sendSensorReset(driver)
+		s.journalDriver(database.JournalReset, DriverTypeSensor, driver, &grID, reason)
 	}
 }
 
@@ -1178,6 +1063,10 @@ func (s *Service) createGroup(runtime gm.GroupConfig) {
 		Hvacs:           cmap.New(),
 		HvacsIssue:      cmap.New(),
 		FirstDay:        cmap.New(),
+		Settings:        cmap.New(),
+	}
+	for _, table := range database.GroupSettingsTables() {
+		group.loadSettings(s.db, table)
 	}
 	for _, sensor := range runtime.Sensors {
 		group.Sensors.Set(sensor, SensorEvent{})
@@ -1214,6 +1103,7 @@ func (s *Service) createGroup(runtime gm.GroupConfig) {
 		//to be sure of the state after a creation or a restart
 		group.HvacsIssue.Set(hvac, true)
 	}
+	s.restoreGroupRuntime(&group)
 	s.groups[runtime.Group] = group
 	s.groupRun(&group)
 }
@@ -1226,7 +1116,7 @@ func (s *Service) stopGroup(group gm.GroupConfig) {
 
 func (s *Service) deleteGroup(group gm.GroupConfig) {
 	s.stopGroup(group)
-	time.Sleep(time.Second)
+	s.clock.Sleep(time.Second)
 
 	gr, _ := s.groups[group.Group]
 	if gr.DbID != "" {
@@ -1234,9 +1124,17 @@ func (s *Service) deleteGroup(group gm.GroupConfig) {
 		s.db.DeleteRecord(pconst.DbConfig, pconst.TbGroups, gr)
 	}
 	delete(s.groups, group.Group)
+	database.RemoveGroupRuntimeState(s.db, group.Group)
+	s.metrics.removeGroup(group.Group)
+	s.groupStatus.Remove(strconv.Itoa(group.Group))
+	s.groupsFirstDay.Remove(strconv.Itoa(group.Group))
 }
 
 func (s *Service) reloadGroupConfig(groupID int, newconfig gm.GroupConfig) {
+	if newconfig.SetpointLeds != nil {
+		//a new LED setpoint overrides the scene first day setpoint
+		s.groupsFirstDay.Remove(strconv.Itoa(groupID))
+	}
 	event := make(map[string]*gm.GroupConfig)
 	event[EventChange] = &newconfig
 	s.groups[groupID].Event <- event
@@ -1567,22 +1465,7 @@ func (s *Service) onGroupCommand(client network.Client, msg network.Message) {
 	// Note send the same command in the cluster
 	topic := "/write/cluster/group/" + strconv.Itoa(grID) + "/commands"
 	s.clusterSendCommand(topic, payloadStr)
-	if _, ok := s.groups[grID]; !ok {
-		rlog.Info("Group " + strconv.Itoa(grID) + " not running on this switch skip it")
-		return
-	}
-	group := dgroup.GroupConfig{
-		Group:              cmd.Group,
-		SetpointLeds:       cmd.Leds,
-		SetpointSlatBlinds: cmd.Slats,
-		SetpointBlinds:     cmd.Blinds,
-		SetpointTempOffset: cmd.TempShift,
-	}
-	if cmd.Leds != nil {
-		auto := false
-		group.Auto = &auto
-	}
-	s.reloadGroupConfig(grID, group)
+	s.applyGroupCommand(cmd)
 }
 
 func (s *Service) onClusterGroupCommand(client network.Client, msg network.Message) {
@@ -1595,10 +1478,34 @@ func (s *Service) onClusterGroupCommand(client network.Client, msg network.Messa
 		rlog.Error("Error during parsing", err.Error())
 		return
 	}
+	s.applyGroupCommand(cmd)
+}
+
+func (s *Service) applyGroupCommand(cmd SwitchCmd) error {
 	grID := cmd.Group
 	if _, ok := s.groups[grID]; !ok {
 		rlog.Info("Group " + strconv.Itoa(grID) + " not running on this switch skip it")
-		return
+		return errors.New("Group " + strconv.Itoa(grID) + " not running on this switch")
+	}
+	if cmd.Scene != nil {
+		return s.recallScene(grID, *cmd.Scene)
+	}
+	button := buttonName(cmd)
+	if cmd.Action != nil && button != "" {
+		gr := s.groups[grID]
+		buttons := gr.buttonSettings()
+		if buttons != nil {
+			s.onButtonEvent(*buttons, button, *cmd.Action)
+			return nil
+		}
+	}
+	if cmd.Channel != nil && (cmd.Blinds != nil || cmd.Slats != nil) {
+		err := s.sendBlindChannelCommand(grID, *cmd.Channel, cmd.Blinds, cmd.Slats)
+		if err != nil {
+			return err
+		}
+		cmd.Blinds = nil
+		cmd.Slats = nil
 	}
 	group := dgroup.GroupConfig{
 		Group:              cmd.Group,
@@ -1612,4 +1519,25 @@ func (s *Service) onClusterGroupCommand(client network.Client, msg network.Messa
 		group.Auto = &auto
 	}
 	s.reloadGroupConfig(grID, group)
+	return nil
+}
+
+//sendBlindChannelCommand forward a blind command on a single channel to the group
+func (s *Service) sendBlindChannelCommand(grID int, channel int, blind *int, slat *int) error {
+	cfg := dgroup.GroupConfig{
+		Group:              grID,
+		SetpointBlinds:     blind,
+		SetpointSlatBlinds: slat,
+	}
+	event := make(map[string]*gm.GroupConfig)
+	switch channel {
+	case database.BlindChannel1:
+		event[EventBlind1] = &cfg
+	case database.BlindChannel2:
+		event[EventBlind2] = &cfg
+	default:
+		return errors.New("Invalid blind channel " + strconv.Itoa(channel))
+	}
+	s.groups[grID].Event <- event
+	return nil
 }
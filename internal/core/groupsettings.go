@@ -0,0 +1,95 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+
+	genericNetwork "github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+//ReloadGroupSettings refresh the settings of a running group after their update in database
+func (s *Service) ReloadGroupSettings(table database.GroupSettingsTable, grID int) {
+	group, ok := s.groups[grID]
+	if !ok {
+		return
+	}
+	group.loadSettings(s.db, table)
+}
+
+//loadSettings cache the settings of the table, read by the group on each tick
+func (group *Group) loadSettings(db database.Database, table database.GroupSettingsTable) {
+	cfg := database.GetGroupSettings(db, table, group.Runtime.Group)
+	if cfg == nil {
+		group.Settings.Remove(table.Table)
+		return
+	}
+	group.Settings.Set(table.Table, cfg)
+}
+
+//settings return the cached settings of the table or nil if none is set
+func (group *Group) settings(table database.GroupSettingsTable) database.GroupSettings {
+	cfg, ok := group.Settings.Get(table.Table)
+	if !ok {
+		return nil
+	}
+	return cfg.(database.GroupSettings)
+}
+
+//readGroupSettings parse the per group settings sent by the server under the key of the table
+func readGroupSettings(table database.GroupSettingsTable, msg genericNetwork.Message) (map[int]json.RawMessage, bool) {
+	payload := msg.Payload()
+	rlog.Debug(msg.Topic() + " : " + string(payload))
+	var config map[string]map[int]json.RawMessage
+	err := json.Unmarshal(payload, &config)
+	if err != nil {
+		rlog.Error("Cannot parse "+table.Name+" ", err.Error())
+		return nil, false
+	}
+	return config[table.Key], true
+}
+
+//onUpdateGroupSettings return the callback saving the per group settings of the table
+func (s *Service) onUpdateGroupSettings(table database.GroupSettingsTable) func(genericNetwork.Client, genericNetwork.Message) {
+	return func(client genericNetwork.Client, msg genericNetwork.Message) {
+		settings, ok := readGroupSettings(table, msg)
+		if !ok {
+			return
+		}
+		for grID, value := range settings {
+			cfg := table.New(grID)
+			err := json.Unmarshal(value, cfg)
+			if err != nil {
+				rlog.Error("Cannot parse " + table.Name + " for group " + strconv.Itoa(grID) + ": " + err.Error())
+				continue
+			}
+			cfg.SetGroup(grID)
+			err = cfg.Check()
+			if err != nil {
+				rlog.Error("Invalid " + table.Name + " for group " + strconv.Itoa(grID) + ": " + err.Error())
+				continue
+			}
+			err = database.SaveGroupSettings(s.db, table, grID, cfg)
+			if err != nil {
+				rlog.Error("Cannot save " + table.Name + " for group " + strconv.Itoa(grID) + ": " + err.Error())
+				continue
+			}
+			s.ReloadGroupSettings(table, grID)
+		}
+	}
+}
+
+//onRemoveGroupSettings return the callback removing the per group settings of the table
+func (s *Service) onRemoveGroupSettings(table database.GroupSettingsTable) func(genericNetwork.Client, genericNetwork.Message) {
+	return func(client genericNetwork.Client, msg genericNetwork.Message) {
+		settings, ok := readGroupSettings(table, msg)
+		if !ok {
+			return
+		}
+		for grID := range settings {
+			database.RemoveGroupSettings(s.db, table, grID)
+			s.ReloadGroupSettings(table, grID)
+		}
+	}
+}
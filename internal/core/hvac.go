@@ -4,11 +4,11 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/energieip/common-components-go/pkg/dhvac"
 	"github.com/energieip/common-components-go/pkg/network"
 	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/energieip/swh200-firmware-go/internal/api"
 	"github.com/energieip/swh200-firmware-go/internal/database"
 	"github.com/romana/rlog"
 )
@@ -120,6 +120,7 @@ func (s *Service) removeHvac(mac string) {
 	criteria := make(map[string]interface{})
 	criteria["Mac"] = mac
 	s.db.DeleteRecord(pconst.DbConfig, pconst.TbHvacs, criteria)
+	s.journalDriverRemoved(DriverTypeHvac, mac)
 	_, ok := s.hvacs.Get(mac)
 	if !ok {
 		return
@@ -178,7 +179,7 @@ func (s *Service) onHvacHello(client network.Client, msg network.Message) {
 		return
 	}
 	driver.Mac = strings.ToUpper(driver.Mac)
-	s.driversSeen.Set(driver.Mac, time.Now().UTC())
+	s.driversSeen.Set(driver.Mac, s.clock.Now().UTC())
 	if driver.DumpFrequency == 0 {
 		driver.DumpFrequency = 1000 //ms default value for hello
 	}
@@ -191,6 +192,7 @@ func (s *Service) onHvacHello(client network.Client, msg network.Message) {
 		rlog.Error("Error during database update ", err.Error())
 		return
 	}
+	s.sendDriverEvent(api.EventDriverHello, DriverTypeHvac, driver.Mac, &driver.Group, driver)
 	rlog.Debug("New Hvac driver stored on database " + driver.Mac)
 
 	cfg := database.GetConfigHvac(s.db, driver.Mac)
@@ -209,7 +211,7 @@ func (s *Service) onHvacStatus(client network.Client, msg network.Message) {
 		return
 	}
 	driver.Mac = strings.ToUpper(driver.Mac)
-	s.driversSeen.Set(driver.Mac, time.Now().UTC())
+	s.driversSeen.Set(driver.Mac, s.clock.Now().UTC())
 	driver.SwitchMac = s.mac
 	if driver.Error == 0 {
 		url := "/read/group/" + strconv.Itoa(driver.Group) + "/events/hvac"
@@ -235,6 +237,7 @@ func (s *Service) onHvacStatus(client network.Client, msg network.Message) {
 		s.sendInvalidHvacStatus(driver)
 	}
 	s.updateHvacStatus(driver)
+	s.sendDriverEvent(api.EventDriverStatus, DriverTypeHvac, driver.Mac, &driver.Group, driver)
 }
 
 func (s *Service) sendInvalidHvacStatus(hvac dhvac.Hvac) {
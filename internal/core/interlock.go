@@ -0,0 +1,112 @@
+package core
+
+import (
+	"strconv"
+	"time"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	"github.com/energieip/common-components-go/pkg/dhvac"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+//WindowInterlock HVAC protection state of a group
+type WindowInterlock struct {
+	OpenedSince time.Time      //zero when the windows are closed
+	Mode        int            //target mode forced on the HVACs
+	Modes       map[string]int //HVAC target modes to restore, nil when the HVACs are not forced
+}
+
+//isInterlocked check if the HVACs of the group are forced by the window interlock
+func (gr *Group) isInterlocked() bool {
+	return gr.Interlock.Modes != nil
+}
+
+//sendHvacTargetMode send an automatic target mode to an HVAC, the HVAC setup keeps the configured one
+func (s *Service) sendHvacTargetMode(mac string, mode int) {
+	_, ok := s.hvacs.Get(mac)
+	if !ok {
+		return
+	}
+	cfg := dhvac.HvacConf{
+		Mac:        mac,
+		TargetMode: &mode,
+	}
+	s.sendHvacUpdate(cfg)
+}
+
+//engageWindowInterlock save the HVACs target modes and force the protection mode
+func (s *Service) engageWindowInterlock(group *Group, mode int) {
+	modes := make(map[string]int)
+	for _, mac := range group.Runtime.Hvacs {
+		previous := 0
+		if group.Runtime.HvacsTargetMode != nil {
+			previous = *group.Runtime.HvacsTargetMode
+		}
+		driver, ok := group.Hvacs.Get(mac)
+		if ok && driver != nil {
+			hvac, err := ToHvacEvent(driver)
+			if err == nil {
+				previous = hvac.TargetMode
+			}
+		}
+		modes[mac] = previous
+		s.sendHvacTargetMode(mac, mode)
+	}
+	group.Interlock.Mode = mode
+	group.Interlock.Modes = modes
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : window opened, force HVACs in target mode " + strconv.Itoa(mode))
+}
+
+//releaseWindowInterlock restore the HVACs target modes
+func (s *Service) releaseWindowInterlock(group *Group) {
+	for mac, mode := range group.Interlock.Modes {
+		s.sendHvacTargetMode(mac, mode)
+	}
+	group.Interlock.Modes = nil
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : window interlock released, HVACs target modes restored")
+}
+
+//interlockSettings return the window interlock settings of the group or nil if none is set
+func (group *Group) interlockSettings() *database.GroupWindowInterlock {
+	cfg, _ := group.settings(database.WindowInterlockSettings).(*database.GroupWindowInterlock)
+	return cfg
+}
+
+//checkWindowInterlock force the HVACs when a window stays opened longer than the interlock delay
+func (s *Service) checkWindowInterlock(group *Group) {
+	cfg := group.interlockSettings()
+	if cfg == nil || !cfg.Enabled || !group.Opened {
+		group.Interlock.OpenedSince = time.Time{}
+		if group.isInterlocked() {
+			s.releaseWindowInterlock(group)
+		}
+		return
+	}
+	now := s.clock.Now()
+	if group.Interlock.OpenedSince.IsZero() {
+		group.Interlock.OpenedSince = now
+	}
+	if group.isInterlocked() {
+		return
+	}
+	if now.Sub(group.Interlock.OpenedSince) >= time.Duration(cfg.GetDelay())*time.Second {
+		s.engageWindowInterlock(group, cfg.GetTargetMode())
+	}
+}
+
+//interlockHvacConfig keep the forced target mode while the interlock is engaged,
+//the requested mode is applied when the window closes
+func (s *Service) interlockHvacConfig(group *Group, groupCfg *gm.GroupConfig) *gm.GroupConfig {
+	if !group.isInterlocked() || groupCfg == nil || groupCfg.HvacsTargetMode == nil {
+		return groupCfg
+	}
+	for _, mac := range group.Runtime.Hvacs {
+		group.Interlock.Modes[mac] = *groupCfg.HvacsTargetMode
+	}
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : window opened, target mode " + strconv.Itoa(*groupCfg.HvacsTargetMode) + " delayed")
+	cfg := *groupCfg
+	mode := group.Interlock.Mode
+	cfg.HvacsTargetMode = &mode
+	return &cfg
+}
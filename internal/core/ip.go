@@ -3,7 +3,6 @@ package core
 import (
 	"io"
 	"os"
-	"os/exec"
 	"time"
 
 	"github.com/romana/rlog"
@@ -50,12 +49,11 @@ func (s *Service) updateIPConfig(ip string, elt sd.SwitchDefinition) {
 		return
 	}
 	if ip == "0" {
-		copyFile(Reference, Configuration)
+		s.sys.SetIPConfig(ip)
 		database.UpdateSwitchConfig(s.db, elt)
 		rlog.Info("Restart Switch to switch in DHCP mode")
-		time.Sleep(5 * time.Second)
-		cmd := exec.Command("reboot")
-		_, err := cmd.CombinedOutput()
+		s.clock.Sleep(5 * time.Second)
+		err := s.sys.Reboot()
 		if err != nil {
 			rlog.Error("Reboot finished with " + err.Error())
 		}
@@ -63,33 +61,47 @@ func (s *Service) updateIPConfig(ip string, elt sd.SwitchDefinition) {
 	}
 
 	rlog.Info("Change IP configuration to " + ip)
+	err := s.sys.SetIPConfig(ip)
+	if err != nil {
+		rlog.Error("Cannot write IP configuration " + err.Error())
+		return
+	}
+	database.UpdateSwitchConfig(s.db, elt)
+	s.clock.Sleep(5 * time.Second)
+
+	rlog.Info("Restart Switch")
+	err = s.sys.Reboot()
+	if err != nil {
+		rlog.Error("Reboot finished with " + err.Error())
+	}
+}
+
+//SetIPConfig update the dhcpcd configuration
+func (sys linuxSystem) SetIPConfig(ip string) error {
+	if ip == "0" {
+		return copyFile(Reference, Configuration)
+	}
 	if _, err := os.Stat(Temp); !os.IsNotExist(err) {
 		os.Remove(Temp)
 	}
 
-	copyFile(Reference, Temp)
+	err := copyFile(Reference, Temp)
+	if err != nil {
+		return err
+	}
 
 	f, err := os.OpenFile(Temp, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
-		return
+		return err
 	}
 
 	dump := "interface eth0\nstatic ip_address=" + ip + "/16\nstatic routers=192.168.0.2\nstatic domain_name_servers=192.168.0.2\n"
 
 	if _, err = f.WriteString(dump); err != nil {
 		f.Close()
-		return
+		return err
 	}
 	f.Close()
-	time.Sleep(1 * time.Second)
-	copyFile(Temp, Configuration)
-	database.UpdateSwitchConfig(s.db, elt)
-	time.Sleep(5 * time.Second)
-
-	rlog.Info("Restart Switch")
-	cmd := exec.Command("reboot")
-	_, err = cmd.CombinedOutput()
-	if err != nil {
-		rlog.Error("Reboot finished with " + err.Error())
-	}
+	sys.clock.Sleep(1 * time.Second)
+	return copyFile(Temp, Configuration)
 }
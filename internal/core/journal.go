@@ -0,0 +1,141 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/api"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	UrlDriverJournal = "events/journal"
+
+	JournalRetention  = 30 * 24 * time.Hour
+	JournalMaxEntries = 5000
+
+	journalPurgeInterval = time.Hour
+	journalPurgeMargin   = 500 //entries removed at once when the journal is full
+)
+
+//driverState driver status fields followed by the journal
+type driverState struct {
+	Group        *int  `json:"group"`
+	Error        int   `json:"error"`
+	IsConfigured *bool `json:"isConfigured"`
+}
+
+//driversJournal last known state of the drivers to detect the status changes
+type driversJournal struct {
+	mutex   sync.Mutex
+	states  map[string]driverState
+	entries int //number of stored entries
+}
+
+//journalDriver record a driver event and forward it to the server
+func (s *Service) journalDriver(event, driverType, mac string, grID *int, reason string) {
+	entry := database.JournalEntry{
+		Date:       s.clock.Now().UTC().Format(time.RFC3339),
+		Mac:        mac,
+		DriverType: driverType,
+		Event:      event,
+		Reason:     reason,
+	}
+	if grID != nil {
+		group := *grID
+		entry.Group = &group
+	}
+	err := database.SaveJournalEntry(s.db, entry)
+	if err != nil {
+		rlog.Error("Cannot save journal entry for " + mac + ": " + err.Error())
+	} else {
+		s.journal.mutex.Lock()
+		s.journal.entries++
+		full := s.journal.entries > JournalMaxEntries
+		s.journal.mutex.Unlock()
+		if full {
+			s.purgeJournal(JournalMaxEntries - journalPurgeMargin)
+		}
+	}
+	dump, _ := json.Marshal(entry)
+	s.serverQueueCommand("/read/switch/"+s.mac+"/"+UrlDriverJournal, string(dump))
+}
+
+//journalDriverEvent record the lifecycle changes carried by a driver event
+func (s *Service) journalDriverEvent(evtType, driverType, mac string, grID *int, data interface{}) {
+	var state driverState
+	inrec, err := json.Marshal(data)
+	if err == nil {
+		json.Unmarshal(inrec, &state)
+	}
+	if state.Group == nil {
+		state.Group = grID
+	}
+
+	s.journal.mutex.Lock()
+	if s.journal.states == nil {
+		s.journal.states = make(map[string]driverState)
+	}
+	last, known := s.journal.states[mac]
+	if evtType == api.EventDriverLost {
+		delete(s.journal.states, mac)
+	} else {
+		s.journal.states[mac] = state
+	}
+	s.journal.mutex.Unlock()
+
+	switch evtType {
+	case api.EventDriverHello:
+		s.journalDriver(database.JournalHello, driverType, mac, state.Group, "")
+	case api.EventDriverLost:
+		s.journalDriver(database.JournalLost, driverType, mac, state.Group, "not seen for 5 dump periods")
+		return
+	}
+	if !known {
+		return
+	}
+	if state.Group != nil && last.Group != nil && *state.Group != *last.Group {
+		s.journalDriver(database.JournalGroup, driverType, mac, state.Group, "group "+strconv.Itoa(*last.Group)+" to "+strconv.Itoa(*state.Group))
+	}
+	if state.IsConfigured != nil && *state.IsConfigured && (last.IsConfigured == nil || !*last.IsConfigured) {
+		s.journalDriver(database.JournalConfigured, driverType, mac, state.Group, "")
+	}
+	if state.Error != last.Error {
+		if state.Error != 0 {
+			s.journalDriver(database.JournalError, driverType, mac, state.Group, "error code "+strconv.Itoa(state.Error))
+		} else {
+			s.journalDriver(database.JournalRecovered, driverType, mac, state.Group, "error code "+strconv.Itoa(last.Error)+" cleared")
+		}
+	}
+}
+
+//journalDriverRemoved record the removal of a driver configuration
+func (s *Service) journalDriverRemoved(driverType, mac string) {
+	s.journal.mutex.Lock()
+	last := s.journal.states[mac]
+	delete(s.journal.states, mac)
+	s.journal.mutex.Unlock()
+	s.journalDriver(database.JournalRemoved, driverType, mac, last.Group, "configuration removed")
+}
+
+//purgeJournal apply the retention and keep at most maxEntries entries
+func (s *Service) purgeJournal(maxEntries int) {
+	entries := database.PurgeJournal(s.db, s.clock.Now().Add(-JournalRetention), maxEntries)
+	s.journal.mutex.Lock()
+	s.journal.entries = entries
+	s.journal.mutex.Unlock()
+}
+
+func (s *Service) cronJournal() {
+	s.purgeJournal(JournalMaxEntries)
+	timer := s.clock.NewTicker(journalPurgeInterval)
+	for {
+		select {
+		case <-timer.C():
+			s.purgeJournal(JournalMaxEntries)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	dl "github.com/energieip/common-components-go/pkg/dled"
 	"github.com/energieip/common-components-go/pkg/network"
 	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/energieip/swh200-firmware-go/internal/api"
 	"github.com/energieip/swh200-firmware-go/internal/database"
 	"github.com/romana/rlog"
 )
@@ -83,6 +84,7 @@ func (s *Service) removeLed(mac string) {
 	criteria := make(map[string]interface{})
 	criteria["Mac"] = mac
 	s.db.DeleteRecord(pconst.DbConfig, pconst.TbLeds, criteria)
+	s.journalDriverRemoved(DriverTypeLed, mac)
 	_, ok := s.ledsToAuto[mac]
 	if ok {
 		delete(s.ledsToAuto, mac)
@@ -159,7 +161,7 @@ func (s *Service) onLedHello(client network.Client, msg network.Message) {
 		return
 	}
 	led.Mac = strings.ToUpper(led.Mac)
-	s.driversSeen.Set(led.Mac, time.Now().UTC())
+	s.driversSeen.Set(led.Mac, s.clock.Now().UTC())
 	led.IsConfigured = false
 	led.SwitchMac = s.mac
 	if led.DumpFrequency == 0 {
@@ -170,6 +172,7 @@ func (s *Service) onLedHello(client network.Client, msg network.Message) {
 		rlog.Error("Error during database update ", err.Error())
 		return
 	}
+	s.sendDriverEvent(api.EventDriverHello, DriverTypeLed, led.Mac, &led.Group, led)
 	rlog.Debugf("New LED driver %v stored on database ", led.Mac)
 
 	cfg := database.GetConfigLed(s.db, led.Mac)
@@ -188,7 +191,7 @@ func (s *Service) onLedStatus(client network.Client, msg network.Message) {
 		return
 	}
 	led.Mac = strings.ToUpper(led.Mac)
-	s.driversSeen.Set(led.Mac, time.Now().UTC())
+	s.driversSeen.Set(led.Mac, s.clock.Now().UTC())
 	led.SwitchMac = s.mac
 	val, ok := s.ledsToAuto[led.Mac]
 	if ok && val != nil {
@@ -209,13 +212,14 @@ func (s *Service) onLedStatus(client network.Client, msg network.Message) {
 	if err != nil {
 		rlog.Error("Error during database update ", err.Error())
 	}
+	s.sendDriverEvent(api.EventDriverStatus, DriverTypeLed, led.Mac, &led.Group, led)
 }
 
 func (s *Service) cronLedMode() {
-	timerDump := time.NewTicker(time.Second)
+	timerDump := s.clock.NewTicker(time.Second)
 	for {
 		select {
-		case <-timerDump.C:
+		case <-timerDump.C():
 			for mac, val := range s.ledsToAuto {
 				if val == nil {
 					continue
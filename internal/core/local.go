@@ -8,11 +8,11 @@ import (
 
 //LocalNetwork network object
 type LocalNetwork struct {
-	Iface genericNetwork.NetworkInterface
+	Iface Broker
 }
 
 func (s *Service) createLocalNetwork() error {
-	driverBroker, err := genericNetwork.NewNetwork(genericNetwork.MQTT)
+	driverBroker, err := s.newBroker()
 	if err != nil {
 		return err
 	}
@@ -72,6 +72,7 @@ func (s *Service) localSendCommand(topic, content string) error {
 	err := s.local.Iface.SendCommand(topic, content)
 	if err != nil {
 		rlog.Error("Local cannot send : " + content + " on: " + topic + " Error: " + err.Error())
+		s.metrics.sendError(BrokerLocal)
 	} else {
 		rlog.Debug("Local Sent : " + content + " on: " + topic)
 	}
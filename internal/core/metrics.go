@@ -0,0 +1,87 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/api"
+)
+
+const (
+	BrokerLocal   = "local"
+	BrokerServer  = "server"
+	BrokerCluster = "cluster"
+)
+
+//serviceMetrics counters exposed on the /metrics endpoint
+type serviceMetrics struct {
+	mutex       sync.Mutex
+	driversLost map[string]uint64
+	sendErrors  map[string]uint64
+	groupTicks  map[int]api.GroupTickMetrics
+}
+
+func (m *serviceMetrics) driverLost(driverType string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.driversLost == nil {
+		m.driversLost = make(map[string]uint64)
+	}
+	m.driversLost[driverType]++
+}
+
+func (m *serviceMetrics) sendError(broker string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.sendErrors == nil {
+		m.sendErrors = make(map[string]uint64)
+	}
+	m.sendErrors[broker]++
+}
+
+func (m *serviceMetrics) groupTick(grID int, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.groupTicks == nil {
+		m.groupTicks = make(map[int]api.GroupTickMetrics)
+	}
+	tick := m.groupTicks[grID]
+	tick.Count++
+	tick.Sum += duration.Seconds()
+	tick.Last = duration.Seconds()
+	m.groupTicks[grID] = tick
+}
+
+func (m *serviceMetrics) removeGroup(grID int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.groupTicks, grID)
+}
+
+//GetMetrics return a copy of the service counters
+func (s *Service) GetMetrics() api.CoreMetrics {
+	s.metrics.mutex.Lock()
+	defer s.metrics.mutex.Unlock()
+	res := api.CoreMetrics{
+		DriversLost: make(map[string]uint64),
+		SendErrors:  make(map[string]uint64),
+		GroupTicks:  make(map[int]api.GroupTickMetrics),
+	}
+	//expose the counters before the first occurrence
+	for _, driverType := range []string{DriverTypeLed, DriverTypeSensor, DriverTypeBlind, DriverTypeHvac, DriverTypeWago, DriverTypeNano} {
+		res.DriversLost[driverType] = 0
+	}
+	for _, broker := range []string{BrokerLocal, BrokerServer, BrokerCluster} {
+		res.SendErrors[broker] = 0
+	}
+	for k, v := range s.metrics.driversLost {
+		res.DriversLost[k] = v
+	}
+	for k, v := range s.metrics.sendErrors {
+		res.SendErrors[k] = v
+	}
+	for k, v := range s.metrics.groupTicks {
+		res.GroupTicks[k] = v
+	}
+	return res
+}
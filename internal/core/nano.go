@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
-	"time"
 
 	dn "github.com/energieip/common-components-go/pkg/dnanosense"
 	"github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/api"
 	"github.com/romana/rlog"
 )
 
@@ -87,11 +87,12 @@ func (s *Service) onNanoStatus(client network.Client, msg network.Message) {
 		return
 	}
 	driver.Mac = strings.ToUpper(driver.Mac)
-	s.driversSeen.Set(driver.Mac, time.Now().UTC())
+	s.driversSeen.Set(driver.Mac, s.clock.Now().UTC())
 	err = s.updateNanoStatus(driver)
 	if err != nil {
 		rlog.Error("Error during database update ", err.Error())
 	}
+	s.sendDriverEvent(api.EventDriverStatus, DriverTypeNano, driver.Mac, &driver.Group, driver)
 	if driver.Error == 0 {
 		url := "/read/group/" + strconv.Itoa(driver.Group) + "/events/nano"
 		evt := NanoEvent{
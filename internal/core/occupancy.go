@@ -0,0 +1,109 @@
+package core
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+//HvacOccupancy presence driven HVAC state of a group
+type HvacOccupancy struct {
+	State          string    //occupied, standby or unoccupied, empty until the first switch
+	Mode           *int      //last target mode sent by the automation or requested explicitly
+	Presence       bool      //presence at the last change
+	Since          time.Time //date of the last presence change
+	Override       bool      //automation suspended by a schedule or manual target mode
+	OverrideExpiry time.Time //zero when the override lasts until the next presence change
+}
+
+//applyHvacTargetMode send a target mode to the group HVACs, kept for the window closing
+//while the window interlock is engaged
+func (s *Service) applyHvacTargetMode(group *Group, mode int) {
+	for _, mac := range group.Runtime.Hvacs {
+		if group.isInterlocked() {
+			group.Interlock.Modes[mac] = mode
+			continue
+		}
+		s.sendHvacTargetMode(mac, mode)
+	}
+}
+
+//occupancySettings return the HVAC occupancy settings of the group or nil if none is set
+func (group *Group) occupancySettings() *database.GroupHvacOccupancy {
+	cfg, _ := group.settings(database.HvacOccupancySettings).(*database.GroupHvacOccupancy)
+	return cfg
+}
+
+//overrideHvacOccupancy suspend the presence automation after a schedule or manual target mode
+func (s *Service) overrideHvacOccupancy(group *Group, mode *int) {
+	cfg := group.occupancySettings()
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	group.Occupancy.Override = true
+	group.Occupancy.OverrideExpiry = time.Time{}
+	if cfg.GetOverride() > 0 {
+		group.Occupancy.OverrideExpiry = s.clock.Now().Add(time.Duration(cfg.GetOverride()) * time.Second)
+	}
+	if mode != nil {
+		value := *mode
+		group.Occupancy.Mode = &value
+	}
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : HVAC occupancy automation overridden")
+}
+
+//occupancyState return the expected occupancy state, empty to keep the current one
+func occupancyState(cfg database.GroupHvacOccupancy, presence bool, elapsed time.Duration) string {
+	if presence {
+		if elapsed >= time.Duration(cfg.GetOccupiedDelay())*time.Second {
+			return database.OccupancyOccupied
+		}
+		return ""
+	}
+	if elapsed >= time.Duration(cfg.GetUnoccupiedDelay())*time.Second {
+		return database.OccupancyUnoccupied
+	}
+	if elapsed >= time.Duration(cfg.GetStandbyDelay())*time.Second {
+		return database.OccupancyStandby
+	}
+	return ""
+}
+
+//checkHvacOccupancy switch the group HVACs target mode according to the group presence
+func (s *Service) checkHvacOccupancy(group *Group) {
+	cfg := group.occupancySettings()
+	if cfg == nil || !cfg.Enabled || group.Sensors.Count() == 0 {
+		group.Occupancy = HvacOccupancy{}
+		return
+	}
+	now := s.clock.Now()
+	occupancy := &group.Occupancy
+	if occupancy.Since.IsZero() || occupancy.Presence != group.Presence {
+		occupancy.Presence = group.Presence
+		occupancy.Since = now
+		if occupancy.Override && occupancy.OverrideExpiry.IsZero() {
+			occupancy.Override = false
+		}
+	}
+	if occupancy.Override {
+		if occupancy.OverrideExpiry.IsZero() || now.Before(occupancy.OverrideExpiry) {
+			return
+		}
+		occupancy.Override = false
+		//apply the current state again
+		occupancy.State = ""
+		rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : HVAC occupancy override expired")
+	}
+
+	state := occupancyState(*cfg, group.Presence, now.Sub(occupancy.Since))
+	if state == "" || state == occupancy.State {
+		return
+	}
+	mode := cfg.GetMode(state)
+	occupancy.State = state
+	occupancy.Mode = &mode
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : HVACs " + state + ", target mode " + strconv.Itoa(mode))
+	s.applyHvacTargetMode(group, mode)
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/romana/rlog"
+)
+
+const (
+	OfflineDropOldest = "dropOldest" //keep the latest messages when the queue is full
+	OfflineDropNewest = "dropNewest" //keep the first messages of the outage
+
+	DefaultOfflinePath        = "/var/lib/energieip-swh200-firmware/offline.jsonl"
+	DefaultOfflineMaxMessages = 1000
+	DefaultOfflineRetention   = 24 * 3600 //s
+
+	offlineReplayInterval = 30 * time.Second
+)
+
+//OfflineConfig offline queue settings, optional "offline" section of the service configuration file
+type OfflineConfig struct {
+	Path        string `json:"path"`        //empty to keep the queue in memory only
+	MaxMessages int    `json:"maxMessages"` //queue size
+	Retention   int    `json:"retention"`   //s, older messages are dropped
+	DropPolicy  string `json:"dropPolicy"`  //dropOldest or dropNewest
+}
+
+//OfflineMessage server message kept while the network broker is unreachable
+type OfflineMessage struct {
+	Date    string `json:"date"` //RFC3339 date of the original message
+	Topic   string `json:"topic"`
+	Content string `json:"content"`
+}
+
+//offlineQueue the messages are appended to the queue file, the dropped and expired ones are
+//only removed from memory: the file is trimmed on load and compacted once it doubled
+type offlineQueue struct {
+	mutex    sync.Mutex
+	conf     OfflineConfig
+	messages []OfflineMessage
+	lines    int //messages written in the queue file, dropped ones included
+	replay   chan bool
+}
+
+//readOfflineConfig read the offline queue settings from the service configuration file
+func readOfflineConfig(confFile string) OfflineConfig {
+	conf := OfflineConfig{
+		Path:        DefaultOfflinePath,
+		MaxMessages: DefaultOfflineMaxMessages,
+		Retention:   DefaultOfflineRetention,
+		DropPolicy:  OfflineDropOldest,
+	}
+	var cfg struct {
+		Offline *OfflineConfig `json:"offline"`
+	}
+	content, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		return conf
+	}
+	err = json.Unmarshal(content, &cfg)
+	if err != nil || cfg.Offline == nil {
+		return conf
+	}
+	conf.Path = cfg.Offline.Path
+	if cfg.Offline.MaxMessages > 0 {
+		conf.MaxMessages = cfg.Offline.MaxMessages
+	}
+	if cfg.Offline.Retention > 0 {
+		conf.Retention = cfg.Offline.Retention
+	}
+	if cfg.Offline.DropPolicy == OfflineDropNewest {
+		conf.DropPolicy = OfflineDropNewest
+	}
+	return conf
+}
+
+//load read the messages kept before a restart
+func (q *offlineQueue) load(now time.Time) {
+	if q.conf.Path == "" {
+		return
+	}
+	file, err := os.Open(q.conf.Path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		q.lines++
+		var msg OfflineMessage
+		err := json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			continue
+		}
+		q.messages = append(q.messages, msg)
+	}
+	q.purge(now)
+	if len(q.messages) > q.conf.MaxMessages {
+		if q.conf.DropPolicy == OfflineDropNewest {
+			q.messages = q.messages[:q.conf.MaxMessages]
+		} else {
+			q.messages = q.messages[len(q.messages)-q.conf.MaxMessages:]
+		}
+	}
+	if len(q.messages) > 0 {
+		rlog.Info(strconv.Itoa(len(q.messages)) + " offline messages to replay")
+	}
+}
+
+//store rewrite the queue file with the pending messages only
+func (q *offlineQueue) store() {
+	if q.conf.Path == "" {
+		return
+	}
+	q.lines = len(q.messages)
+	if len(q.messages) == 0 {
+		os.Remove(q.conf.Path)
+		return
+	}
+	os.MkdirAll(filepath.Dir(q.conf.Path), 0755)
+	tmp := q.conf.Path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		rlog.Error("Cannot store offline messages " + err.Error())
+		return
+	}
+	writer := bufio.NewWriter(file)
+	for _, msg := range q.messages {
+		dump, _ := json.Marshal(msg)
+		writer.Write(dump)
+		writer.WriteString("\n")
+	}
+	writer.Flush()
+	file.Close()
+	os.Rename(tmp, q.conf.Path)
+}
+
+//append add a message at the end of the queue file
+func (q *offlineQueue) append(msg OfflineMessage) {
+	if q.conf.Path == "" {
+		return
+	}
+	os.MkdirAll(filepath.Dir(q.conf.Path), 0755)
+	file, err := os.OpenFile(q.conf.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		rlog.Error("Cannot store offline message " + err.Error())
+		return
+	}
+	defer file.Close()
+	dump, _ := json.Marshal(msg)
+	file.Write(append(dump, '\n'))
+	q.lines++
+}
+
+//purge drop the messages older than the retention
+func (q *offlineQueue) purge(now time.Time) {
+	limit := now.Add(-time.Duration(q.conf.Retention) * time.Second)
+	kept := q.messages[:0]
+	for _, msg := range q.messages {
+		date, err := time.Parse(time.RFC3339, msg.Date)
+		if err == nil && date.Before(limit) {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	q.messages = kept
+}
+
+//push queue a message according to the drop policy
+func (q *offlineQueue) push(msg OfflineMessage, now time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.purge(now)
+	if len(q.messages) >= q.conf.MaxMessages {
+		if q.conf.DropPolicy == OfflineDropNewest {
+			rlog.Warn("Offline queue full: drop message on " + msg.Topic)
+			return
+		}
+		q.messages = q.messages[len(q.messages)-q.conf.MaxMessages+1:]
+	}
+	q.messages = append(q.messages, msg)
+	if q.lines >= 2*q.conf.MaxMessages {
+		q.store()
+	} else {
+		q.append(msg)
+	}
+}
+
+//isEmpty check if messages are waiting for the replay
+func (q *offlineQueue) isEmpty() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.messages) == 0
+}
+
+//serverQueueCommand send a message to the server, or keep it for a later replay when the
+//network broker is unreachable
+func (s *Service) serverQueueCommand(topic, content string) error {
+	msg := OfflineMessage{
+		Date:    s.clock.Now().UTC().Format(time.RFC3339),
+		Topic:   topic,
+		Content: content,
+	}
+	if !s.offline.isEmpty() {
+		//keep the messages order until the replay is done
+		s.offline.push(msg, s.clock.Now())
+		s.triggerOfflineReplay()
+		return nil
+	}
+	err := s.serverSendCommand(topic, content)
+	if err != nil {
+		s.offline.push(msg, s.clock.Now())
+	}
+	return err
+}
+
+func (s *Service) triggerOfflineReplay() {
+	select {
+	case s.offline.replay <- true:
+	default:
+	}
+}
+
+//replayContent add the original date in the offlineDate field of a JSON object message
+func replayContent(msg OfflineMessage) string {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal([]byte(msg.Content), &fields)
+	if err != nil || fields == nil {
+		return msg.Content
+	}
+	date, _ := json.Marshal(msg.Date)
+	fields["offlineDate"] = date
+	dump, err := json.Marshal(fields)
+	if err != nil {
+		return msg.Content
+	}
+	return string(dump)
+}
+
+//replayOffline send the queued messages in order on their topic with their original date
+func (s *Service) replayOffline() {
+	s.offline.mutex.Lock()
+	defer s.offline.mutex.Unlock()
+	s.offline.purge(s.clock.Now())
+	if len(s.offline.messages) == 0 {
+		if s.offline.lines > 0 {
+			s.offline.store()
+		}
+		return
+	}
+	sent := 0
+	for _, msg := range s.offline.messages {
+		err := s.server.Iface.SendCommand(msg.Topic, replayContent(msg))
+		if err != nil {
+			break
+		}
+		sent++
+	}
+	if sent == 0 {
+		return
+	}
+	rlog.Info(strconv.Itoa(sent) + " offline messages replayed")
+	s.offline.messages = s.offline.messages[sent:]
+	s.offline.store()
+}
+
+func (s *Service) cronOfflineReplay() {
+	timer := s.clock.NewTicker(offlineReplayInterval)
+	for {
+		select {
+		case <-timer.C():
+			s.replayOffline()
+		case <-s.offline.replay:
+			s.replayOffline()
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"time"
+
+	genericNetwork "github.com/energieip/common-components-go/pkg/network"
+	pkg "github.com/energieip/common-components-go/pkg/service"
+	"github.com/energieip/common-components-go/pkg/tools"
+	"github.com/energieip/swh200-firmware-go/internal/api"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+//Hardware access to the switch GPIOs (PSE, KSZ switch, BAES and push buttons)
+type Hardware interface {
+	WriteGPIO(gpio int, value int) error
+	ReadGPIO(gpio int) (int, error)
+}
+
+//System switch system actions
+type System interface {
+	Reboot() error
+	//SetIPConfig write the dhcpcd configuration, "0" means DHCP
+	SetIPConfig(ip string) error
+	Upgrade()
+	//NetworkInfo return the MAC and IP addresses of the switch
+	NetworkInfo() (string, string)
+}
+
+//Ticker periodic time events
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+//Timer single time event
+type Timer interface {
+	Stop() bool
+}
+
+//Clock time source of the service
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+//Broker connection to a MQTT broker
+type Broker interface {
+	Initialize(config genericNetwork.NetworkConfig) error
+	Disconnect()
+	SendCommand(topic, content string) error
+}
+
+//Platform dependencies of the service; nil fields use the switch implementation
+type Platform struct {
+	Hardware  Hardware
+	System    System
+	Clock     Clock
+	NewBroker func() (Broker, error)
+	Database  database.Database  //already connected database, RethinkDB from the configuration otherwise
+	APIServer api.HTTPServer     //TLS server on the configured API address otherwise
+	Config    *pkg.ServiceConfig //parsed service configuration, read from the configuration file otherwise
+}
+
+//NewService create a service running on the given platform
+func NewService(platform Platform) *Service {
+	s := Service{}
+	s.setPlatform(platform)
+	return &s
+}
+
+func (s *Service) setPlatform(platform Platform) {
+	s.hw = platform.Hardware
+	if s.hw == nil {
+		s.hw = gpioHardware{}
+	}
+	s.clock = platform.Clock
+	if s.clock == nil {
+		s.clock = systemClock{}
+	}
+	s.sys = platform.System
+	if s.sys == nil {
+		s.sys = linuxSystem{clock: s.clock}
+	}
+	s.newBroker = platform.NewBroker
+	if s.newBroker == nil {
+		s.newBroker = newMQTTBroker
+	}
+	s.db = platform.Database
+	s.apiServer = platform.APIServer
+	s.platformConf = platform.Config
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+type systemClock struct{}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (c systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+func (c systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (sys linuxSystem) NetworkInfo() (string, string) {
+	return tools.GetNetworkInfo()
+}
+
+func newMQTTBroker() (Broker, error) {
+	return genericNetwork.NewNetwork(genericNetwork.MQTT)
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	RuntimeSaveInterval = time.Minute //refresh period of an unchanged runtime state
+)
+
+func (s *Service) groupRuntimeState(group *Group) database.GroupRuntimeState {
+	state := database.GroupRuntimeState{
+		Group:              group.Runtime.Group,
+		Auto:               !s.isManualMode(group),
+		Setpoint:           group.Setpoint,
+		FirstDaySetpoint:   group.FirstDaySetpoint,
+		TimeToAuto:         group.TimeToAuto,
+		SetpointBlinds:     group.SetpointBlinds,
+		SetpointSlatBlinds: group.SetpointSlatBlinds,
+		ShiftTemp:          group.ShiftTemp,
+		Presence:           group.Presence,
+	}
+	if group.isInterlocked() {
+		mode := group.Interlock.Mode
+		state.InterlockMode = &mode
+		state.InterlockModes = make(map[string]int)
+		for mac, value := range group.Interlock.Modes {
+			state.InterlockModes[mac] = value
+		}
+	}
+	return state
+}
+
+//sameRuntimeState compare two states without the date and the countdown to the automatic mode
+func sameRuntimeState(a database.GroupRuntimeState, b database.GroupRuntimeState) bool {
+	a.Date = ""
+	b.Date = ""
+	a.TimeToAuto = 0
+	b.TimeToAuto = 0
+	dumpA, _ := json.Marshal(a)
+	dumpB, _ := json.Marshal(b)
+	return string(dumpA) == string(dumpB)
+}
+
+//saveGroupRuntime persist the group runtime state on change and every RuntimeSaveInterval
+func (s *Service) saveGroupRuntime(group *Group) {
+	state := s.groupRuntimeState(group)
+	now := s.clock.Now().UTC()
+	if group.RuntimeSaved != nil && sameRuntimeState(state, *group.RuntimeSaved) && now.Sub(group.RuntimeSavedDate) < RuntimeSaveInterval {
+		return
+	}
+	state.Date = now.Format(time.RFC3339)
+	err := database.SaveGroupRuntimeState(s.db, state)
+	if err != nil {
+		rlog.Error("Cannot save runtime state of group " + strconv.Itoa(state.Group) + ": " + err.Error())
+		return
+	}
+	group.RuntimeSaved = &state
+	group.RuntimeSavedDate = now
+}
+
+//restoreGroupRuntime apply the stored runtime state of a group when it is recent enough
+func (s *Service) restoreGroupRuntime(group *Group) {
+	if s.runtimeMaxAge <= 0 {
+		return
+	}
+	state := database.GetGroupRuntimeState(s.db, group.Runtime.Group)
+	if state == nil {
+		return
+	}
+	date, err := time.Parse(time.RFC3339, state.Date)
+	if err != nil {
+		return
+	}
+	age := s.clock.Now().UTC().Sub(date)
+	if age < 0 || age > s.runtimeMaxAge {
+		rlog.Info("Group " + strconv.Itoa(state.Group) + " runtime state too old: skip it")
+		return
+	}
+	auto := state.Auto
+	group.Runtime.Auto = &auto
+	group.Setpoint = state.Setpoint
+	group.FirstDaySetpoint = state.FirstDaySetpoint
+	group.TimeToAuto = state.TimeToAuto - int(age.Seconds())
+	if group.TimeToAuto < 0 {
+		group.TimeToAuto = 0
+	}
+	group.SetpointBlinds = state.SetpointBlinds
+	group.SetpointSlatBlinds = state.SetpointSlatBlinds
+	group.ShiftTemp = state.ShiftTemp
+	if state.Presence {
+		//keep the room occupied until the sensors report again
+		group.Presence = true
+		group.LastPresenceStatus = true
+		if group.Runtime.RulePresence != nil {
+			group.PresenceTimeout = *group.Runtime.RulePresence
+		}
+	}
+	if state.InterlockMode != nil && state.InterlockModes != nil {
+		//restored on the first tick if the windows are closed
+		group.Interlock.Mode = *state.InterlockMode
+		group.Interlock.Modes = state.InterlockModes
+	}
+	rlog.Info("Group " + strconv.Itoa(state.Group) + " runtime state of " + state.Date + " restored")
+}
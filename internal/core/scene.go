@@ -0,0 +1,87 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	genericNetwork "github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+//SwitchScenes group scenes sent by the server
+type SwitchScenes struct {
+	Scenes []database.GroupScene `json:"scenes"`
+}
+
+func (s *Service) onUpdateScenes(client genericNetwork.Client, msg genericNetwork.Message) {
+	payload := msg.Payload()
+	rlog.Debug(msg.Topic() + " : " + string(payload))
+	var config SwitchScenes
+	err := json.Unmarshal(payload, &config)
+	if err != nil {
+		rlog.Error("Cannot parse scenes ", err.Error())
+		return
+	}
+	for _, scene := range config.Scenes {
+		err = scene.Check()
+		if err != nil {
+			rlog.Error("Invalid scene for group " + strconv.Itoa(scene.Group) + ": " + err.Error())
+			continue
+		}
+		err = database.SaveGroupScene(s.db, scene)
+		if err != nil {
+			rlog.Error("Cannot save scene " + scene.Scene + " for group " + strconv.Itoa(scene.Group) + ": " + err.Error())
+		}
+	}
+}
+
+func (s *Service) onRemoveScenes(client genericNetwork.Client, msg genericNetwork.Message) {
+	payload := msg.Payload()
+	rlog.Debug(msg.Topic() + " : " + string(payload))
+	var config SwitchScenes
+	err := json.Unmarshal(payload, &config)
+	if err != nil {
+		rlog.Error("Cannot parse scenes ", err.Error())
+		return
+	}
+	for _, scene := range config.Scenes {
+		database.RemoveGroupScene(s.db, scene.Group, scene.Scene)
+	}
+}
+
+//RecallGroupScene apply a stored scene on a group running on the switch
+func (s *Service) RecallGroupScene(grID int, scene string) error {
+	if _, ok := s.groups[grID]; !ok {
+		return errors.New("Group " + strconv.Itoa(grID) + " not running on this switch")
+	}
+	return s.recallScene(grID, scene)
+}
+
+func (s *Service) recallScene(grID int, sceneID string) error {
+	scene := database.GetGroupScene(s.db, grID, sceneID)
+	if scene == nil {
+		rlog.Warn("Group " + strconv.Itoa(grID) + " unknown scene " + sceneID)
+		return errors.New("Scene " + sceneID + " not found for group " + strconv.Itoa(grID))
+	}
+	rlog.Info("Group " + strconv.Itoa(grID) + " recall scene " + sceneID)
+	group := gm.GroupConfig{
+		Group:              grID,
+		SetpointLeds:       scene.SetpointLeds,
+		SetpointBlinds:     scene.SetpointBlinds,
+		SetpointSlatBlinds: scene.SetpointSlats,
+		SetpointTempOffset: scene.SetpointTempOffset,
+	}
+	if scene.SetpointLeds != nil || scene.SetpointLedsFirstDay != nil {
+		auto := false
+		group.Auto = &auto
+	}
+	s.reloadGroupConfig(grID, group)
+	if scene.SetpointLedsFirstDay != nil {
+		//LED setpoints are re-applied every second in manual mode
+		s.groupsFirstDay.Set(strconv.Itoa(grID), *scene.SetpointLedsFirstDay)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"strconv"
+	"time"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	//ScheduleCatchUp maximum delay for which the missed schedule events are still applied
+	ScheduleCatchUp = 10 * time.Minute
+)
+
+//cronSchedules apply the group schedules; it only relies on the local database
+//and keeps running when the server is not reachable
+func (s *Service) cronSchedules() {
+	last := s.clock.Now()
+	timer := s.clock.NewTicker(10 * time.Second)
+	for {
+		select {
+		case <-timer.C():
+			now := s.clock.Now()
+			if now.Truncate(time.Minute).Equal(last.Truncate(time.Minute)) {
+				continue
+			}
+			s.checkSchedules(last, now)
+			last = now
+		}
+	}
+}
+
+//checkSchedules apply the events planned in ]from, to]
+func (s *Service) checkSchedules(from time.Time, to time.Time) {
+	schedules := database.GetGroupsSchedule(s.db)
+	if len(schedules) == 0 {
+		return
+	}
+	start := from.Truncate(time.Minute).Add(time.Minute)
+	if to.Sub(start) > ScheduleCatchUp {
+		//clock update or long freeze: do not replay the whole period
+		start = to.Truncate(time.Minute).Add(-ScheduleCatchUp)
+	}
+	for date := start; !date.After(to); date = date.Add(time.Minute) {
+		for grID, sched := range schedules {
+			if _, ok := s.groups[grID]; !ok {
+				continue
+			}
+			for _, evt := range sched.EventsAt(date) {
+				s.applyScheduleEvent(grID, evt)
+			}
+		}
+	}
+}
+
+func (s *Service) applyScheduleEvent(grID int, evt database.ScheduleEvent) {
+	rlog.Info("Group " + strconv.Itoa(grID) + " apply schedule event of " + evt.Time)
+	if evt.Calibration != nil && *evt.Calibration {
+		err := s.StartGroupCalibration(grID, database.CalibrationRequest{})
+		if err != nil {
+			rlog.Warn("Group " + strconv.Itoa(grID) + " cannot start calibration: " + err.Error())
+		}
+	}
+	if evt.Auto == nil && evt.SetpointLeds == nil && evt.SetpointBlinds == nil && evt.SetpointSlats == nil && evt.HvacsTargetMode == nil {
+		return
+	}
+	group := gm.GroupConfig{
+		Group:              grID,
+		Auto:               evt.Auto,
+		SetpointLeds:       evt.SetpointLeds,
+		SetpointBlinds:     evt.SetpointBlinds,
+		SetpointSlatBlinds: evt.SetpointSlats,
+		HvacsTargetMode:    evt.HvacsTargetMode,
+	}
+	if evt.SetpointLeds != nil && evt.Auto == nil {
+		auto := false
+		group.Auto = &auto
+	}
+	s.reloadGroupConfig(grID, group)
+}
@@ -4,12 +4,12 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/energieip/common-components-go/pkg/pconst"
 
 	ds "github.com/energieip/common-components-go/pkg/dsensor"
 	"github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/api"
 	"github.com/energieip/swh200-firmware-go/internal/database"
 	"github.com/romana/rlog"
 )
@@ -83,11 +83,17 @@ func (s *Service) updateSensorStatus(sensor ds.Sensor) error {
 	return nil
 }
 
-func (s *Service) prepareSensorSetup(sensor ds.SensorSetup) {
-	err := database.SaveSensorSetup(s.db, sensor)
+func (s *Service) prepareSensorSetup(sensor database.SensorSetup) {
+	err := sensor.Check()
+	if err != nil {
+		rlog.Error("Invalid sensor setup", err.Error())
+		return
+	}
+	err = database.SaveSensorSetup(s.db, sensor)
 	if err != nil {
 		rlog.Error("Cannot update database", err.Error())
 	}
+	s.sensorWeights.Remove(sensor.Mac)
 	c, ok := s.sensors.Get(sensor.Mac)
 	if ok {
 		cell := c.(ds.Sensor)
@@ -98,27 +104,31 @@ func (s *Service) prepareSensorSetup(sensor ds.SensorSetup) {
 	}
 }
 
-func (s *Service) updateSensorConfig(cfg ds.SensorConf) {
+//updateSensorConfig update the sensor setup and forward it to the sensor, false when the setup is not saved
+func (s *Service) updateSensorConfig(cfg ds.SensorConf) bool {
 	setup, dbID := database.GetSensorConfig(s.db, cfg.Mac)
 	if setup == nil || dbID == "" {
-		return
+		return false
 	}
 	new := ds.UpdateConfig(cfg, *setup)
 	err := s.db.UpdateRecord(pconst.DbConfig, pconst.TbSensors, dbID, &new)
 	if err != nil {
 		rlog.Error("Error updating database" + err.Error())
-		return
+		return false
 	}
 	_, ok := s.sensors.Get(cfg.Mac)
 	if ok {
 		s.sendSensorUpdate(cfg)
 	}
+	return true
 }
 
 func (s *Service) removeSensor(mac string) {
 	criteria := make(map[string]interface{})
 	criteria["Mac"] = mac
 	s.db.DeleteRecord(pconst.DbConfig, pconst.TbSensors, criteria)
+	s.sensorWeights.Remove(mac)
+	s.journalDriverRemoved(DriverTypeSensor, mac)
 	_, ok := s.sensors.Get(mac)
 	if ok {
 		isConfigured := false
@@ -132,6 +142,17 @@ func (s *Service) removeSensor(mac string) {
 	}
 }
 
+//sensorWeight return the weight of a sensor in the group weighted rule, cached from its setup
+func (s *Service) sensorWeight(mac string) float64 {
+	weight, ok := s.sensorWeights.Get(mac)
+	if ok {
+		return weight.(float64)
+	}
+	value := database.GetSensorWeight(s.db, mac)
+	s.sensorWeights.Set(mac, value)
+	return value
+}
+
 func (s *Service) sendSensorReset(mac string) {
 	configured := false
 	driver := ds.SensorConf{
@@ -150,7 +171,7 @@ func (s *Service) onSensorHello(client network.Client, msg network.Message) {
 		return
 	}
 	sensor.Mac = strings.ToUpper(sensor.Mac)
-	s.driversSeen.Set(sensor.Mac, time.Now().UTC())
+	s.driversSeen.Set(sensor.Mac, s.clock.Now().UTC())
 	sensor.IsConfigured = false
 	sensor.SwitchMac = s.mac
 	if sensor.DumpFrequency == 0 {
@@ -161,6 +182,7 @@ func (s *Service) onSensorHello(client network.Client, msg network.Message) {
 		rlog.Error("Error during database update ", err.Error())
 		return
 	}
+	s.sendDriverEvent(api.EventDriverHello, DriverTypeSensor, sensor.Mac, &sensor.Group, sensor)
 	cfg := database.GetConfigSensor(s.db, sensor.Mac)
 	if cfg != nil {
 		s.sendSensorSetup(*cfg)
@@ -176,7 +198,7 @@ func (s *Service) onSensorStatus(client network.Client, msg network.Message) {
 		return
 	}
 	sensor.Mac = strings.ToUpper(sensor.Mac)
-	s.driversSeen.Set(sensor.Mac, time.Now().UTC())
+	s.driversSeen.Set(sensor.Mac, s.clock.Now().UTC())
 	sensor.SwitchMac = s.mac
 	sensor.Brightness = sensor.BrightnessRaw
 	cfg := database.GetConfigSensor(s.db, sensor.Mac)
@@ -202,6 +224,7 @@ func (s *Service) onSensorStatus(client network.Client, msg network.Message) {
 	if err != nil {
 		rlog.Error("Error during database update ", err.Error())
 	}
+	s.sendDriverEvent(api.EventDriverStatus, DriverTypeSensor, sensor.Mac, &sensor.Group, sensor)
 
 	if sensor.Error == 0 {
 		url := "/read/group/" + strconv.Itoa(sensor.Group) + "/events/sensor"
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"math"
+	"sort"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+//sensorRules return the sensor rules of the group, empty when none is set
+func (group *Group) sensorRules() database.GroupSensorRules {
+	cfg, _ := group.settings(database.SensorRuleSettings).(*database.GroupSensorRules)
+	if cfg == nil {
+		return *database.SensorRuleSettings.New(group.Runtime.Group).(*database.GroupSensorRules)
+	}
+	return *cfg
+}
+
+//sensorRule return the rule of a group metric, the group sensor rule by default
+func sensorRule(group *Group, rules database.GroupSensorRules, metric string) string {
+	rule := gm.SensorAverage
+	if group.Runtime.SensorRule != nil {
+		rule = *group.Runtime.SensorRule
+	}
+	return rules.GetRule(metric, rule)
+}
+
+//setpointRule return the rule of the HVACs setpoints: only average, max or min apply to setpoints
+func setpointRule(group *Group, rules database.GroupSensorRules) string {
+	rule := sensorRule(group, rules, database.SensorMetricTemperature)
+	if isSetpointRule(rule) {
+		return rule
+	}
+	if group.Runtime.SensorRule != nil && isSetpointRule(*group.Runtime.SensorRule) {
+		return *group.Runtime.SensorRule
+	}
+	return gm.SensorAverage
+}
+
+func isSetpointRule(rule string) bool {
+	return rule == gm.SensorAverage || rule == gm.SensorMax || rule == gm.SensorMin
+}
+
+//unitWeight weight of the drivers without setup weight
+func unitWeight(mac string) float64 {
+	return database.DefaultSensorWeight
+}
+
+func averageValues(values map[string]float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func medianValue(values map[string]float64) float64 {
+	sorted := make([]float64, 0, len(values))
+	for _, value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+//aggregateValues compute the group value from the readings of its valid drivers (by MAC) and their weights
+func aggregateValues(values map[string]float64, rule string, rules database.GroupSensorRules, weightOf func(mac string) float64) int {
+	if len(values) == 0 {
+		return 0
+	}
+	result := 0.0
+	switch rule {
+	case gm.SensorMax:
+		result = math.Inf(-1)
+		for _, value := range values {
+			result = math.Max(result, value)
+		}
+	case gm.SensorMin:
+		result = math.Inf(1)
+		for _, value := range values {
+			result = math.Min(result, value)
+		}
+	case database.SensorWeighted:
+		sum := 0.0
+		weights := 0.0
+		for mac, value := range values {
+			weight := weightOf(mac)
+			sum += weight * value
+			weights += weight
+		}
+		if weights > 0 {
+			result = sum / weights
+		} else {
+			result = averageValues(values)
+		}
+	case database.SensorMedian:
+		result = medianValue(values)
+	case database.SensorOutlier:
+		median := medianValue(values)
+		deviation := math.Abs(median) * float64(rules.GetOutlierDeviation()) / 100
+		kept := make(map[string]float64)
+		for mac, value := range values {
+			if math.Abs(value-median) <= deviation {
+				kept[mac] = value
+			}
+		}
+		if len(kept) == 0 {
+			//too scattered readings
+			result = median
+		} else {
+			result = averageValues(kept)
+		}
+	default:
+		result = averageValues(values)
+	}
+	return int(math.Round(result))
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"strconv"
+	"testing"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	ds "github.com/energieip/common-components-go/pkg/dsensor"
+	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+func TestSensorWeightedRule(t *testing.T) {
+	db := database.NewMemoryDatabase()
+	db.CreateDB(pconst.DbConfig)
+	db.CreateTable(pconst.DbConfig, pconst.TbSensors, &database.SensorSetup{})
+	s := &Service{db: db, sensors: cmap.New(), sensorWeights: cmap.New()}
+	weight := 3.0
+	setup := database.SensorSetup{
+		SensorSetup: ds.SensorSetup{Mac: "AA:00:00:00:00:01"},
+		Weight:      &weight,
+	}
+	s.prepareSensorSetup(setup)
+
+	values := map[string]float64{
+		"AA:00:00:00:00:01": 400,
+		"AA:00:00:00:00:02": 200,
+	}
+	rules := database.GroupSensorRules{}
+	result := aggregateValues(values, database.SensorWeighted, rules, s.sensorWeight)
+	if result != 350 {
+		t.Fatalf("weighted value %v, expected 350", result)
+	}
+
+	//a new setup replaces the cached weight
+	weight = 0
+	s.prepareSensorSetup(setup)
+	result = aggregateValues(values, database.SensorWeighted, rules, s.sensorWeight)
+	if result != 200 {
+		t.Errorf("weighted value %v with an ignored sensor, expected 200", result)
+	}
+}
+
+func TestSetpointRule(t *testing.T) {
+	groupRule := gm.SensorMax
+	group := &Group{Runtime: gm.GroupConfig{Group: 1, SensorRule: &groupRule}}
+	weighted := database.SensorWeighted
+	rules := database.GroupSensorRules{Group: 1, Temperature: &weighted}
+	if rule := setpointRule(group, rules); rule != gm.SensorMax {
+		t.Errorf("setpoint rule %v, expected the group rule %v", rule, gm.SensorMax)
+	}
+
+	median := database.SensorMedian
+	group.Runtime.SensorRule = &median
+	if rule := setpointRule(group, rules); rule != gm.SensorAverage {
+		t.Errorf("setpoint rule %v, expected %v", rule, gm.SensorAverage)
+	}
+
+	min := gm.SensorMin
+	rules.Temperature = &min
+	if rule := setpointRule(group, rules); rule != gm.SensorMin {
+		t.Errorf("setpoint rule %v, expected %v", rule, gm.SensorMin)
+	}
+}
+
+func TestAggregateValues(t *testing.T) {
+	deviation := func(value int) *int {
+		return &value
+	}
+	cases := []struct {
+		name      string
+		values    []float64
+		rule      string
+		deviation *int
+		expected  int
+	}{
+		{"no reading", nil, gm.SensorAverage, nil, 0},
+		{"average", []float64{10, 20, 40}, gm.SensorAverage, nil, 23},
+		{"max", []float64{10, 20, 40}, gm.SensorMax, nil, 40},
+		{"min", []float64{10, 20, 40}, gm.SensorMin, nil, 10},
+		{"median odd count", []float64{30, 10, 20}, database.SensorMedian, nil, 20},
+		{"median even count", []float64{50, 10, 30, 20}, database.SensorMedian, nil, 25},
+		{"median single reading", []float64{42}, database.SensorMedian, nil, 42},
+		{"outlier default deviation", []float64{80, 100, 150}, database.SensorOutlier, nil, 90},
+		{"outlier default deviation drops a faulty sensor", []float64{95, 100, 105, 400}, database.SensorOutlier, nil, 100},
+		{"outlier wide deviation keeps all", []float64{80, 100, 150}, database.SensorOutlier, deviation(60), 110},
+		{"outlier narrow deviation", []float64{80, 100, 150}, database.SensorOutlier, deviation(10), 100},
+		{"outlier all dropped fall back to the median", []float64{10, 30, 70, 200}, database.SensorOutlier, nil, 50},
+		{"outlier all dropped with deviation", []float64{10, 30, 70, 200}, database.SensorOutlier, deviation(30), 50},
+		{"outlier deviation keeps the closest readings", []float64{20, 30, 100, 200}, database.SensorOutlier, deviation(80), 50},
+		{"unknown rule is an average", []float64{10, 20}, "unknown", nil, 15},
+	}
+	for _, c := range cases {
+		values := make(map[string]float64)
+		for i, value := range c.values {
+			values["AA:00:00:00:00:0"+strconv.Itoa(i)] = value
+		}
+		rules := database.GroupSensorRules{OutlierDeviation: c.deviation}
+		result := aggregateValues(values, c.rule, rules, unitWeight)
+		if result != c.expected {
+			t.Errorf("%v: value %v, expected %v", c.name, result, c.expected)
+		}
+	}
+}
+
+func TestSensorRuleByMetric(t *testing.T) {
+	median := database.SensorMedian
+	average := gm.SensorAverage
+	rules := database.GroupSensorRules{Group: 1, Brightness: &median, AirQuality: &average}
+	groupRule := gm.SensorMax
+	cases := []struct {
+		name      string
+		groupRule *string
+		metric    string
+		expected  string
+	}{
+		{"brightness rule", &groupRule, database.SensorMetricBrightness, database.SensorMedian},
+		{"air quality rule", &groupRule, database.SensorMetricAirQuality, gm.SensorAverage},
+		{"temperature follows the group rule", &groupRule, database.SensorMetricTemperature, gm.SensorMax},
+		{"temperature without group rule", nil, database.SensorMetricTemperature, gm.SensorAverage},
+		{"brightness without group rule", nil, database.SensorMetricBrightness, database.SensorMedian},
+	}
+	for _, c := range cases {
+		group := &Group{Runtime: gm.GroupConfig{Group: 1, SensorRule: c.groupRule}}
+		if rule := sensorRule(group, rules, c.metric); rule != c.expected {
+			t.Errorf("%v: rule %v, expected %v", c.name, rule, c.expected)
+		}
+	}
+}
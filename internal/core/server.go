@@ -7,29 +7,37 @@ import (
 
 	sd "github.com/energieip/common-components-go/pkg/dswitch"
 	genericNetwork "github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/database"
 	"github.com/romana/rlog"
 )
 
 const (
-	EventServerSetup  = "serverSetup"
-	EventServerReload = "serverReload"
-	EventServerRemove = "serverRemove"
+	EventServerSetup   = "serverSetup"
+	EventServerReload  = "serverReload"
+	EventServerRemove  = "serverRemove"
+	EventServerRestore = "serverRestore"
 )
 
+//SwitchSetup switch configuration sent by the server with the sensors weights
+type SwitchSetup struct {
+	sd.SwitchConfig
+	SensorsSetup map[string]database.SensorSetup `json:"sensorsSetup"`
+}
+
 //ServerNetwork network object
 type ServerNetwork struct {
-	Iface  genericNetwork.NetworkInterface
-	Events chan map[string]sd.SwitchConfig
+	Iface  Broker
+	Events chan map[string]SwitchSetup
 }
 
 func (s *Service) createServerNetwork() error {
-	serverBroker, err := genericNetwork.NewNetwork(genericNetwork.MQTT)
+	serverBroker, err := s.newBroker()
 	if err != nil {
 		return err
 	}
 	serverNet := ServerNetwork{
 		Iface:  serverBroker,
-		Events: make(chan map[string]sd.SwitchConfig),
+		Events: make(chan map[string]SwitchSetup),
 	}
 	s.server = serverNet
 	return nil
@@ -41,6 +49,16 @@ func (s *Service) remoteServerConnection() error {
 	cbkServer["/write/switch/"+s.mac+"/setup/config"] = s.onSetup
 	cbkServer["/write/switch/"+s.mac+"/update/settings"] = s.onUpdateSetting
 	cbkServer["/remove/switch/"+s.mac+"/update/settings"] = s.onRemoveSetting
+	cbkServer["/write/switch/"+s.mac+"/update/scenes"] = s.onUpdateScenes
+	cbkServer["/remove/switch/"+s.mac+"/update/scenes"] = s.onRemoveScenes
+	cbkServer["/write/switch/"+s.mac+"/update/alarms"] = s.onUpdateAlarmRules
+	cbkServer["/remove/switch/"+s.mac+"/update/alarms"] = s.onRemoveAlarmRules
+	for _, table := range database.GroupSettingsTables() {
+		cbkServer["/write/switch/"+s.mac+"/update/"+table.Key] = s.onUpdateGroupSettings(table)
+		cbkServer["/remove/switch/"+s.mac+"/update/"+table.Key] = s.onRemoveGroupSettings(table)
+	}
+	cbkServer["/write/switch/"+s.mac+"/backup/export"] = s.onBackupExport
+	cbkServer["/write/switch/"+s.mac+"/backup/restore"] = s.onBackupRestore
 
 	confServer := genericNetwork.NetworkConfig{
 		IP:        s.conf.NetworkBroker.IP,
@@ -58,30 +76,26 @@ func (s *Service) remoteServerConnection() error {
 		err := s.server.Iface.Initialize(confServer)
 		if err == nil {
 			rlog.Info("Connected to server broker " + s.conf.NetworkBroker.IP)
+			s.triggerOfflineReplay()
 			return err
 		}
-		timer := time.NewTicker(time.Second)
 		rlog.Error("Cannot connect to broker " + s.conf.NetworkBroker.IP + " error: " + err.Error())
 		rlog.Error("Try to reconnect " + s.conf.NetworkBroker.IP + " in 1s")
-
-		select {
-		case <-timer.C:
-			continue
-		}
+		s.clock.Sleep(time.Second)
 	}
 }
 
 func (s *Service) onSetup(client genericNetwork.Client, msg genericNetwork.Message) {
 	payload := msg.Payload()
 	rlog.Debug(msg.Topic() + " : " + string(payload))
-	var switchConf sd.SwitchConfig
+	var switchConf SwitchSetup
 	err := json.Unmarshal(payload, &switchConf)
 	if err != nil {
 		rlog.Error("Cannot parse config ", err.Error())
 		return
 	}
 	switchConf.Mac = strings.ToUpper(switchConf.Mac)
-	event := make(map[string]sd.SwitchConfig)
+	event := make(map[string]SwitchSetup)
 	event[EventServerSetup] = switchConf
 	s.server.Events <- event
 }
@@ -89,14 +103,14 @@ func (s *Service) onSetup(client genericNetwork.Client, msg genericNetwork.Messa
 func (s *Service) onRemoveSetting(client genericNetwork.Client, msg genericNetwork.Message) {
 	payload := msg.Payload()
 	rlog.Debug(msg.Topic() + " : " + string(payload))
-	var switchConf sd.SwitchConfig
+	var switchConf SwitchSetup
 	err := json.Unmarshal(payload, &switchConf)
 	if err != nil {
 		rlog.Error("Cannot parse config ", err.Error())
 		return
 	}
 	switchConf.Mac = strings.ToUpper(switchConf.Mac)
-	event := make(map[string]sd.SwitchConfig)
+	event := make(map[string]SwitchSetup)
 	event[EventServerRemove] = switchConf
 	s.server.Events <- event
 }
@@ -104,14 +118,14 @@ func (s *Service) onRemoveSetting(client genericNetwork.Client, msg genericNetwo
 func (s *Service) onUpdateSetting(client genericNetwork.Client, msg genericNetwork.Message) {
 	payload := msg.Payload()
 	rlog.Debug(msg.Topic() + " : " + string(payload))
-	var switchConf sd.SwitchConfig
+	var switchConf SwitchSetup
 	err := json.Unmarshal(payload, &switchConf)
 	if err != nil {
 		rlog.Error("Cannot parse config ", err.Error())
 		return
 	}
 	switchConf.Mac = strings.ToUpper(switchConf.Mac)
-	event := make(map[string]sd.SwitchConfig)
+	event := make(map[string]SwitchSetup)
 	event[EventServerReload] = switchConf
 	s.server.Events <- event
 }
@@ -124,6 +138,7 @@ func (s *Service) serverSendCommand(topic, content string) error {
 	err := s.server.Iface.SendCommand(topic, content)
 	if err != nil {
 		rlog.Error("Server Cannot send : " + content + " on: " + topic + " Error: " + err.Error())
+		s.metrics.sendError(BrokerServer)
 	} else {
 		rlog.Debug("Server sent : " + content + " on: " + topic)
 	}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/energieip/common-components-go/pkg/dblind"
+	"github.com/energieip/common-components-go/pkg/dhvac"
+	dl "github.com/energieip/common-components-go/pkg/dled"
+	dn "github.com/energieip/common-components-go/pkg/dnanosense"
+	ds "github.com/energieip/common-components-go/pkg/dsensor"
+	"github.com/energieip/common-components-go/pkg/dwago"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+)
+
+//isDriverAlive check that the driver was seen within 5 dump periods
+func (s *Service) isDriverAlive(mac string, dumpFrequency int, timeNow time.Time) bool {
+	val, ok := s.driversSeen.Get(mac)
+	if !ok || val == nil {
+		return false
+	}
+	maxDuration := time.Duration(5*dumpFrequency) * time.Millisecond
+	return timeNow.Sub(val.(time.Time)) <= maxDuration
+}
+
+//GetLeds return the LEDs currently seen by the switch
+func (s *Service) GetLeds() map[string]dl.Led {
+	timeNow := s.clock.Now().UTC()
+	drivers := make(map[string]dl.Led)
+	for _, dr := range s.leds.Items() {
+		driver, err := dl.ToLed(dr)
+		if err != nil {
+			continue
+		}
+		if s.isDriverAlive(driver.Mac, driver.DumpFrequency, timeNow) {
+			drivers[driver.Mac] = *driver
+		}
+	}
+	return drivers
+}
+
+//GetSensors return the sensors currently seen by the switch
+func (s *Service) GetSensors() map[string]ds.Sensor {
+	timeNow := s.clock.Now().UTC()
+	drivers := make(map[string]ds.Sensor)
+	for _, dr := range s.sensors.Items() {
+		driver, err := ds.ToSensor(dr)
+		if err != nil {
+			continue
+		}
+		if s.isDriverAlive(driver.Mac, driver.DumpFrequency, timeNow) {
+			drivers[driver.Mac] = *driver
+		}
+	}
+	return drivers
+}
+
+//GetBlinds return the blinds currently seen by the switch
+func (s *Service) GetBlinds() map[string]dblind.Blind {
+	timeNow := s.clock.Now().UTC()
+	drivers := make(map[string]dblind.Blind)
+	for _, dr := range s.blinds.Items() {
+		driver, err := dblind.ToBlind(dr)
+		if err != nil {
+			continue
+		}
+		if s.isDriverAlive(driver.Mac, driver.DumpFrequency, timeNow) {
+			drivers[driver.Mac] = *driver
+		}
+	}
+	return drivers
+}
+
+//GetHvacs return the HVACs currently seen by the switch
+func (s *Service) GetHvacs() map[string]dhvac.Hvac {
+	timeNow := s.clock.Now().UTC()
+	drivers := make(map[string]dhvac.Hvac)
+	for _, dr := range s.hvacs.Items() {
+		driver, err := dhvac.ToHvac(dr)
+		if err != nil {
+			continue
+		}
+		if s.isDriverAlive(driver.Mac, driver.DumpFrequency, timeNow) {
+			drivers[driver.Mac] = *driver
+		}
+	}
+	return drivers
+}
+
+//GetWagos return the WAGOs currently seen by the switch
+func (s *Service) GetWagos() map[string]dwago.Wago {
+	timeNow := s.clock.Now().UTC()
+	drivers := make(map[string]dwago.Wago)
+	for _, dr := range s.wagos.Items() {
+		driver, err := dwago.ToWago(dr)
+		if err != nil {
+			continue
+		}
+		if s.isDriverAlive(driver.Mac, driver.DumpFrequency, timeNow) {
+			drivers[driver.Mac] = *driver
+		}
+	}
+	return drivers
+}
+
+//GetNanos return the nanosenses currently seen by the switch
+func (s *Service) GetNanos() map[string]dn.Nanosense {
+	timeNow := s.clock.Now().UTC()
+	drivers := make(map[string]dn.Nanosense)
+	for _, dr := range s.nanos.Items() {
+		driver, err := dn.ToNanosense(dr)
+		if err != nil {
+			continue
+		}
+		if s.isDriverAlive(driver.Mac, driver.DumpFrequency, timeNow) {
+			drivers[driver.Mac] = *driver
+		}
+	}
+	return drivers
+}
+
+//GetGroupsStatus return the status of the groups running on the switch
+func (s *Service) GetGroupsStatus() map[int]database.GroupStatus {
+	groups := make(map[int]database.GroupStatus)
+	for _, elt := range s.groupStatus.Items() {
+		gr, err := database.ToGroupStatus(elt)
+		if err != nil {
+			continue
+		}
+		groups[gr.Group] = *gr
+	}
+	return groups
+}
+
+//SendGroupCommand apply a group command (same payload as on /write/group/+/commands)
+func (s *Service) SendGroupCommand(grID int, payload []byte) error {
+	var cmd SwitchCmd
+	err := json.Unmarshal(payload, &cmd)
+	if err != nil {
+		return err
+	}
+	cmd.Group = grID
+	return s.applyGroupCommand(cmd)
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"time"
+)
+
+//LocationConfig switch location, optional "location" section of the service configuration file
+type LocationConfig struct {
+	Latitude  float64 `json:"latitude"`  //degrees, positive in the north
+	Longitude float64 `json:"longitude"` //degrees, positive in the east
+}
+
+//readLocationConfig read the switch location from the service configuration file, nil when unset
+func readLocationConfig(confFile string) *LocationConfig {
+	var cfg struct {
+		Location *LocationConfig `json:"location"`
+	}
+	content, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		return nil
+	}
+	err = json.Unmarshal(content, &cfg)
+	if err != nil {
+		return nil
+	}
+	return cfg.Location
+}
+
+//sunPosition compute the sun azimuth (degrees from the north, clockwise) and elevation (degrees)
+//with the NOAA approximation, accurate enough for the blinds
+func sunPosition(date time.Time, latitude float64, longitude float64) (float64, float64) {
+	date = date.UTC()
+	hour := float64(date.Hour()) + float64(date.Minute())/60 + float64(date.Second())/3600
+	gamma := 2 * math.Pi / 365 * (float64(date.YearDay()-1) + (hour-12)/24)
+	eqTime := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+	decl := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+
+	//true solar time in minutes
+	solarTime := hour*60 + eqTime + 4*longitude
+	hourAngle := (solarTime/4 - 180) * math.Pi / 180
+	lat := latitude * math.Pi / 180
+
+	cosZenith := math.Sin(lat)*math.Sin(decl) + math.Cos(lat)*math.Cos(decl)*math.Cos(hourAngle)
+	zenith := math.Acos(math.Max(-1, math.Min(1, cosZenith)))
+	elevation := 90 - zenith*180/math.Pi
+	azimuth := math.Atan2(math.Sin(hourAngle), math.Cos(hourAngle)*math.Sin(lat)-math.Tan(decl)*math.Cos(lat))
+	azimuth = math.Mod(azimuth*180/math.Pi+540, 360)
+	return azimuth, elevation
+}
+
+//angleDiff return the signed difference between two azimuths in [-180, 180[
+func angleDiff(a float64, b float64) float64 {
+	return math.Mod(a-b+540, 360) - 180
+}
@@ -9,14 +9,16 @@ import (
 )
 
 type SwitchCmd struct {
-	Group     int   `json:"group"`
-	Leds      *int  `json:"leds,omitempty"`
-	Slats     *int  `json:"slats,omitempty"`
-	Blinds    *int  `json:"blinds,omitempty"`
-	TempShift *int  `json:"heat,omitempty"`     //temperature shift in 1/10°C
-	Action    *bool `json:"action,omitempty"`   //true/false   (press/release)
-	ButtonA   *bool `json:"button_A,omitempty"` //true/false  (0/1)
-	ButtonB   *bool `json:"button_B,omitempty"` //true/false  (0/1)
+	Group     int     `json:"group"`
+	Leds      *int    `json:"leds,omitempty"`
+	Slats     *int    `json:"slats,omitempty"`
+	Blinds    *int    `json:"blinds,omitempty"`
+	Channel   *int    `json:"channel,omitempty"`  //blind channel 1 or 2 for the blinds and slats, both when unset
+	TempShift *int    `json:"heat,omitempty"`     //temperature shift in 1/10°C
+	Action    *bool   `json:"action,omitempty"`   //true/false   (press/release)
+	ButtonA   *bool   `json:"button_A,omitempty"` //true/false  (0/1)
+	ButtonB   *bool   `json:"button_B,omitempty"` //true/false  (0/1)
+	Scene     *string `json:"scene,omitempty"`    //scene id to recall
 }
 
 func (s *Service) onSwitchCmd(client network.Client, msg network.Message) {
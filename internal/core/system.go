@@ -67,3 +67,18 @@ func SystemUpgrade() {
 
 	rlog.Warn("Ask for a system reboot????")
 }
+
+//linuxSystem system actions of the switch
+type linuxSystem struct {
+	clock Clock
+}
+
+func (sys linuxSystem) Reboot() error {
+	cmd := exec.Command("reboot")
+	_, err := cmd.CombinedOutput()
+	return err
+}
+
+func (sys linuxSystem) Upgrade() {
+	SystemUpgrade()
+}
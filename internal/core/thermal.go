@@ -0,0 +1,184 @@
+package core
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/energieip/common-components-go/pkg/dhvac"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	ThermalModeNone = 0
+	ThermalModeHeat = 1
+	ThermalModeCool = -1
+)
+
+//ThermalControl switch side temperature controller state of a group
+type ThermalControl struct {
+	PI          PIController //demand in %, positive when heating and negative when cooling
+	Mode        int          //heat or cool, kept until the opposite setpoint is crossed
+	Last        time.Time    //date of the last correction
+	Demand      *int         //nil when the controller is not running
+	Valve       *int         //last 6 ways valve position sent
+	Damper      *int         //last damper position sent
+	ValveClosed int          //positions to restore when the controller is stopped
+	DamperMin   int
+}
+
+//thermalSetpoints return the heat and cool setpoints (1/10°C) of the current occupancy state
+func thermalSetpoints(group *Group) (int, int) {
+	state := group.Occupancy.State
+	if state == "" {
+		state = database.OccupancyUnoccupied
+		if group.Presence || group.Sensors.Count() == 0 {
+			state = database.OccupancyOccupied
+		}
+	}
+	switch state {
+	case database.OccupancyStandby:
+		return group.StandbyHeat, group.StandbyCool
+	case database.OccupancyUnoccupied:
+		return group.UnoccupHeat, group.UnoccupCool
+	}
+	return group.OccupHeat, group.OccupCool
+}
+
+//thermalPositions convert a demand into the valve and damper positions
+func thermalPositions(cfg database.GroupThermalControl, demand int) (int, int) {
+	closed := cfg.GetValveClosed()
+	valve := closed
+	if demand > 0 {
+		valve = closed + demand*(100-closed)/100
+	} else if demand < 0 {
+		valve = closed + demand*closed/100
+	}
+	damper := demand
+	if damper < 0 {
+		damper = -damper
+	}
+	if damper < cfg.GetDamperMin() {
+		damper = cfg.GetDamperMin()
+	}
+	return valve, damper
+}
+
+//sendThermalPositions forward the valve and damper positions to the group HVACs when they changed
+func (s *Service) sendThermalPositions(group *Group, valve int, damper int) {
+	thermal := &group.Thermal
+	if thermal.Valve != nil && *thermal.Valve == valve && thermal.Damper != nil && *thermal.Damper == damper {
+		return
+	}
+	thermal.Valve = &valve
+	thermal.Damper = &damper
+	for _, mac := range group.Runtime.Hvacs {
+		_, ok := s.hvacs.Get(mac)
+		if !ok {
+			continue
+		}
+		//runtime command only: the controller outputs are not part of the HVAC setup
+		forcingValve := valve
+		forcingDamper := damper
+		s.sendHvacUpdate(dhvac.HvacConf{
+			Mac:               mac,
+			Forcing6waysValve: &forcingValve,
+			ForcingDamper:     &forcingDamper,
+		})
+	}
+}
+
+//stopThermalControl restore the valves and dampers at rest when the controller is disabled
+func (s *Service) stopThermalControl(group *Group) {
+	thermal := group.Thermal
+	group.Thermal = ThermalControl{}
+	if thermal.Valve == nil {
+		return
+	}
+	rlog.Info("Group " + strconv.Itoa(group.Runtime.Group) + " : thermal control stopped")
+	s.sendThermalPositions(group, thermal.ValveClosed, thermal.DamperMin)
+	group.Thermal = ThermalControl{}
+}
+
+//thermalSettings return the thermal control settings of the group or nil if none is set
+func (group *Group) thermalSettings() *database.GroupThermalControl {
+	cfg, _ := group.settings(database.ThermalControlSettings).(*database.GroupThermalControl)
+	return cfg
+}
+
+//checkThermalControl compute the group heating or cooling demand and drive the HVACs valves and dampers
+func (s *Service) checkThermalControl(group *Group) {
+	cfg := group.thermalSettings()
+	if cfg == nil || !cfg.Enabled || len(group.Runtime.Hvacs) == 0 {
+		s.stopThermalControl(group)
+		return
+	}
+	temperature, ok := group.roomTemperature()
+	if !ok {
+		//no valid temperature measure
+		s.stopThermalControl(group)
+		return
+	}
+	interval := 10
+	if group.Runtime.CorrectionInterval != nil {
+		interval = *group.Runtime.CorrectionInterval
+	}
+	now := s.clock.Now()
+	thermal := &group.Thermal
+	elapsed := now.Sub(thermal.Last)
+	if !thermal.Last.IsZero() && elapsed < time.Duration(interval)*time.Second {
+		return
+	}
+	dt := float64(interval)
+	if !thermal.Last.IsZero() {
+		dt = elapsed.Seconds()
+	}
+	thermal.Last = now
+	thermal.ValveClosed = cfg.GetValveClosed()
+	thermal.DamperMin = cfg.GetDamperMin()
+
+	heat, cool := thermalSetpoints(group)
+	mode := thermal.Mode
+	if heat == 0 && cool == 0 {
+		//setpoints not yet reported by the HVACs
+		mode = ThermalModeNone
+	} else if temperature < heat {
+		mode = ThermalModeHeat
+	} else if temperature > cool {
+		mode = ThermalModeCool
+	}
+	if group.isInterlocked() {
+		mode = ThermalModeNone
+	}
+	if mode != thermal.Mode {
+		thermal.PI.Reset(0)
+		thermal.Mode = mode
+	}
+
+	ctrl := &thermal.PI
+	ctrl.Kp = cfg.GetKp()
+	ctrl.Ki = cfg.GetKi()
+	ctrl.DeadBand = float64(cfg.GetDeadBand()) / 10
+	demand := 0
+	switch mode {
+	case ThermalModeHeat:
+		ctrl.OutMin = 0
+		ctrl.OutMax = 100
+		demand = int(math.Round(ctrl.Update(float64(heat)/10, float64(temperature)/10, dt)))
+	case ThermalModeCool:
+		ctrl.OutMin = -100
+		ctrl.OutMax = 0
+		demand = int(math.Round(ctrl.Update(float64(cool)/10, float64(temperature)/10, dt)))
+	}
+	if thermal.Demand == nil || *thermal.Demand != demand {
+		rlog.Debug("Group " + strconv.Itoa(group.Runtime.Group) + " : thermal demand " + strconv.Itoa(demand) + "%")
+	}
+	thermal.Demand = &demand
+	valve, damper := thermalPositions(*cfg, demand)
+	if group.AirQuality.Demand > damper {
+		//the air quality ventilation needs more fresh air
+		damper = group.AirQuality.Demand
+	}
+	s.sendThermalPositions(group, valve, damper)
+}
@@ -3,11 +3,11 @@ package core
 import (
 	"encoding/json"
 	"strings"
-	"time"
 
 	"github.com/energieip/common-components-go/pkg/dwago"
 	"github.com/energieip/common-components-go/pkg/network"
 	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/energieip/swh200-firmware-go/internal/api"
 	"github.com/energieip/swh200-firmware-go/internal/database"
 	"github.com/romana/rlog"
 )
@@ -74,6 +74,7 @@ func (s *Service) removeWago(mac string) {
 	criteria := make(map[string]interface{})
 	criteria["Mac"] = mac
 	s.db.DeleteRecord(pconst.DbConfig, pconst.TbWagos, criteria)
+	s.journalDriverRemoved(DriverTypeWago, mac)
 
 	_, ok := s.wagos.Get(mac)
 	if !ok {
@@ -142,7 +143,7 @@ func (s *Service) onWagoHello(client network.Client, msg network.Message) {
 		return
 	}
 	driver.Mac = strings.ToUpper(driver.Mac)
-	s.driversSeen.Set(driver.Mac, time.Now().UTC())
+	s.driversSeen.Set(driver.Mac, s.clock.Now().UTC())
 	if driver.DumpFrequency == 0 {
 		driver.DumpFrequency = 1000 //ms default value
 	}
@@ -152,6 +153,7 @@ func (s *Service) onWagoHello(client network.Client, msg network.Message) {
 		rlog.Error("Error during database update ", err.Error())
 		return
 	}
+	s.sendDriverEvent(api.EventDriverHello, DriverTypeWago, driver.Mac, nil, driver)
 	cfg, _ := database.GetWagoConfig(s.db, driver.Mac)
 	if cfg != nil {
 		s.sendWagoSetup(*cfg)
@@ -168,11 +170,12 @@ func (s *Service) onWagoStatus(client network.Client, msg network.Message) {
 		return
 	}
 	driver.Mac = strings.ToUpper(driver.Mac)
-	s.driversSeen.Set(driver.Mac, time.Now().UTC())
+	s.driversSeen.Set(driver.Mac, s.clock.Now().UTC())
 	err = s.updateWagoStatus(driver)
 	if err != nil {
 		rlog.Error("Error during database update ", err.Error())
 	}
+	s.sendDriverEvent(api.EventDriverStatus, DriverTypeWago, driver.Mac, nil, driver)
 	if driver.Error == 0 {
 		consigne := 0
 		for _, cron := range driver.CronJobs {
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	AirQualityGood     = 0 //measures below the thresholds
+	AirQualityModerate = 1 //ventilation running
+	AirQualityPoor     = 2 //measures above the maximum values
+
+	DefaultAirQualityCO2Threshold = 1000 //in ppm
+	DefaultAirQualityCO2Max       = 1500 //in ppm
+	DefaultAirQualityHysteresis   = 10   //in % of the thresholds
+	DefaultAirQualityMinRunTime   = 600  //in s
+	DefaultAirQualityDamperMin    = 20   //in %
+)
+
+//GroupAirQuality indoor air quality driven ventilation of a group
+type GroupAirQuality struct {
+	Group        int            `json:"group"`
+	Enabled      bool           `json:"enabled"`
+	CO2Threshold *int           `json:"co2Threshold,omitempty"` //ppm, the ventilation starts above it
+	CO2Max       *int           `json:"co2Max,omitempty"`       //ppm, the dampers are fully opened above it
+	COVThreshold *int           `json:"covThreshold,omitempty"` //the COV is not used when not set
+	COVMax       *int           `json:"covMax,omitempty"`
+	Hysteresis   *int           `json:"hysteresis,omitempty"` //% of the thresholds, the ventilation stops below threshold - hysteresis
+	MinRunTime   *int           `json:"minRunTime,omitempty"` //s, minimum ventilation duration
+	DamperMin    *int           `json:"damperMin,omitempty"`  //damper position when the ventilation starts in %
+	OpenBlinds   bool           `json:"openBlinds"`           //raise the group blinds when the ventilation starts
+	Windows      map[string]int `json:"windows,omitempty"`    //window actuators opened during the ventilation: channel 1, 2 or 0 for both by blind driver MAC
+}
+
+//Check validate the air quality settings
+func (cfg GroupAirQuality) Check() error {
+	if cfg.GetCO2Threshold() <= 0 || cfg.GetCO2Max() <= cfg.GetCO2Threshold() {
+		return errors.New("Invalid CO2 thresholds")
+	}
+	if cfg.COVThreshold != nil && *cfg.COVThreshold <= 0 {
+		return errors.New("Invalid COV threshold")
+	}
+	if cfg.COVMax != nil && (cfg.COVThreshold == nil || *cfg.COVMax <= *cfg.COVThreshold) {
+		return errors.New("Invalid COV maximum")
+	}
+	if cfg.Hysteresis != nil && (*cfg.Hysteresis < 0 || *cfg.Hysteresis > 100) {
+		return errors.New("Invalid hysteresis")
+	}
+	if cfg.MinRunTime != nil && *cfg.MinRunTime < 0 {
+		return errors.New("Invalid negative minimum run time")
+	}
+	if cfg.DamperMin != nil && (*cfg.DamperMin < 0 || *cfg.DamperMin > 100) {
+		return errors.New("Invalid damper minimum position")
+	}
+	for mac, channel := range cfg.Windows {
+		if channel != BlindChannelBoth && channel != BlindChannel1 && channel != BlindChannel2 {
+			return errors.New("Invalid window channel for blind " + mac)
+		}
+	}
+	return nil
+}
+
+//GetCO2Threshold return the CO2 level starting the ventilation
+func (cfg GroupAirQuality) GetCO2Threshold() int {
+	return intOrDefault(cfg.CO2Threshold, DefaultAirQualityCO2Threshold)
+}
+
+//GetCO2Max return the CO2 level fully opening the dampers
+func (cfg GroupAirQuality) GetCO2Max() int {
+	return intOrDefault(cfg.CO2Max, DefaultAirQualityCO2Max)
+}
+
+//GetCOVMax return the COV level fully opening the dampers, twice the threshold by default
+func (cfg GroupAirQuality) GetCOVMax() int {
+	if cfg.COVThreshold == nil {
+		return 0
+	}
+	return intOrDefault(cfg.COVMax, 2*(*cfg.COVThreshold))
+}
+
+//GetHysteresis return the hysteresis in % of the thresholds
+func (cfg GroupAirQuality) GetHysteresis() int {
+	return intOrDefault(cfg.Hysteresis, DefaultAirQualityHysteresis)
+}
+
+//GetMinRunTime return the minimum ventilation duration
+func (cfg GroupAirQuality) GetMinRunTime() int {
+	return intOrDefault(cfg.MinRunTime, DefaultAirQualityMinRunTime)
+}
+
+//GetDamperMin return the damper position when the ventilation starts
+func (cfg GroupAirQuality) GetDamperMin() int {
+	return intOrDefault(cfg.DamperMin, DefaultAirQualityDamperMin)
+}
+
+//AirQualitySettings air quality settings configuration table
+var AirQualitySettings = GroupSettingsTable{
+	Name:  "air quality settings",
+	Key:   "airQuality",
+	Table: AirQualityTable,
+	New: func(grID int) GroupSettings {
+		return &GroupAirQuality{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the air quality settings
+func (cfg *GroupAirQuality) SetGroup(grID int) {
+	cfg.Group = grID
+}
+
+//normalize store the window actuators MAC in upper case
+func (cfg *GroupAirQuality) normalize() {
+	if cfg.Windows == nil {
+		return
+	}
+	windows := make(map[string]int)
+	for mac, channel := range cfg.Windows {
+		windows[strings.ToUpper(mac)] = channel
+	}
+	cfg.Windows = windows
+}
@@ -0,0 +1,230 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+const (
+	AlarmInfo     = "info"
+	AlarmWarning  = "warning"
+	AlarmCritical = "critical"
+
+	//group metrics, temperatures in °C
+	AlarmMetricCO2           = "co2"
+	AlarmMetricCOV           = "cov"
+	AlarmMetricTemperature   = "temperature"
+	AlarmMetricHygrometry    = "hygrometry"
+	AlarmMetricBrightness    = "brightness"
+	AlarmMetricPresence      = "presence"      //0 or 1
+	AlarmMetricWindowsOpened = "windowsOpened" //0 or 1
+	AlarmMetricHvacsHeatCool = "hvacsHeatCool" //heat/cool state reported by the group HVACs
+	//driver metrics
+	AlarmMetricError     = "error"
+	AlarmMetricLinePower = "linePower"
+
+	AlarmGreater        = ">"
+	AlarmGreaterOrEqual = ">="
+	AlarmLower          = "<"
+	AlarmLowerOrEqual   = "<="
+	AlarmEqual          = "=="
+	AlarmDifferent      = "!="
+	AlarmOutside        = "outside" //out of the [value, high] range
+)
+
+var groupAlarmMetrics = map[string]bool{
+	AlarmMetricCO2:           true,
+	AlarmMetricCOV:           true,
+	AlarmMetricTemperature:   true,
+	AlarmMetricHygrometry:    true,
+	AlarmMetricBrightness:    true,
+	AlarmMetricPresence:      true,
+	AlarmMetricWindowsOpened: true,
+	AlarmMetricHvacsHeatCool: true,
+}
+
+var driverAlarmMetrics = map[string]bool{
+	AlarmMetricError:     true,
+	AlarmMetricLinePower: true,
+}
+
+//AlarmCondition comparison of a metric with a threshold
+type AlarmCondition struct {
+	Metric     string   `json:"metric"`
+	Operator   string   `json:"operator"`       //>, >=, <, <=, ==, != or outside
+	Value      float64  `json:"value"`          //threshold or low bound of the outside range
+	High       *float64 `json:"high,omitempty"` //high bound of the outside range
+	Hysteresis float64  `json:"hysteresis"`     //margin to cross back before the condition ends
+}
+
+//AlarmRule alarm raised when all the conditions are fulfilled
+//the rule applies to a group or a driver, to all the groups or drivers when none is set
+type AlarmRule struct {
+	Name       string           `json:"name"` //rule identifier
+	Label      string           `json:"label,omitempty"`
+	Severity   string           `json:"severity"` //info, warning or critical
+	Group      *int             `json:"group,omitempty"`
+	Mac        string           `json:"mac,omitempty"`
+	Conditions []AlarmCondition `json:"conditions"`
+	RaiseDelay int              `json:"raiseDelay"` //s, the conditions must last to raise the alarm
+	ClearDelay int              `json:"clearDelay"` //s, the conditions must be over to clear the alarm
+}
+
+//Alarm raised or cleared alarm
+type Alarm struct {
+	Rule     string             `json:"rule"`
+	Label    string             `json:"label,omitempty"`
+	Severity string             `json:"severity"`
+	Group    *int               `json:"group,omitempty"`
+	Mac      string             `json:"mac,omitempty"`
+	Active   bool               `json:"active"`
+	Values   map[string]float64 `json:"values"` //measures of the conditions metrics
+	Raised   string             `json:"raised"` //RFC3339 UTC date
+	Cleared  string             `json:"cleared,omitempty"`
+}
+
+//Check validate the condition content
+func (cond AlarmCondition) Check() error {
+	if !groupAlarmMetrics[cond.Metric] && !driverAlarmMetrics[cond.Metric] {
+		return errors.New("Unknown metric " + cond.Metric)
+	}
+	switch cond.Operator {
+	case AlarmGreater, AlarmGreaterOrEqual, AlarmLower, AlarmLowerOrEqual, AlarmEqual, AlarmDifferent:
+	case AlarmOutside:
+		if cond.High == nil || *cond.High < cond.Value {
+			return errors.New("Invalid range for " + cond.Metric)
+		}
+	default:
+		return errors.New("Unknown operator " + cond.Operator)
+	}
+	if cond.Hysteresis < 0 {
+		return errors.New("Invalid hysteresis for " + cond.Metric)
+	}
+	return nil
+}
+
+//Check validate the rule content
+func (rule AlarmRule) Check() error {
+	if rule.Name == "" {
+		return errors.New("Missing rule name")
+	}
+	switch rule.Severity {
+	case AlarmInfo, AlarmWarning, AlarmCritical:
+	default:
+		return errors.New("Unknown severity " + rule.Severity)
+	}
+	if len(rule.Conditions) == 0 {
+		return errors.New("No condition in rule " + rule.Name)
+	}
+	for i, cond := range rule.Conditions {
+		err := cond.Check()
+		if err != nil {
+			return err
+		}
+		if i > 0 && groupAlarmMetrics[cond.Metric] != rule.IsGroupRule() {
+			return errors.New("Rule " + rule.Name + " mixes group and driver metrics")
+		}
+	}
+	if rule.IsGroupRule() && rule.Mac != "" {
+		return errors.New("Rule " + rule.Name + " uses group metrics on a driver")
+	}
+	if rule.RaiseDelay < 0 || rule.ClearDelay < 0 {
+		return errors.New("Invalid delay " + strconv.Itoa(rule.RaiseDelay) + "/" + strconv.Itoa(rule.ClearDelay))
+	}
+	return nil
+}
+
+//IsGroupRule check if the rule is evaluated on the groups, on the drivers otherwise
+func (rule AlarmRule) IsGroupRule() bool {
+	return len(rule.Conditions) > 0 && groupAlarmMetrics[rule.Conditions[0].Metric]
+}
+
+//Matches check if the condition is fulfilled; an active condition lasts until the value
+//crosses back the threshold by the hysteresis
+func (cond AlarmCondition) Matches(value float64, active bool) bool {
+	margin := 0.0
+	if active {
+		margin = cond.Hysteresis
+	}
+	switch cond.Operator {
+	case AlarmGreater:
+		return value > cond.Value-margin
+	case AlarmGreaterOrEqual:
+		return value >= cond.Value-margin
+	case AlarmLower:
+		return value < cond.Value+margin
+	case AlarmLowerOrEqual:
+		return value <= cond.Value+margin
+	case AlarmEqual:
+		return value == cond.Value
+	case AlarmDifferent:
+		return value != cond.Value
+	case AlarmOutside:
+		return cond.High != nil && (value < cond.Value+margin || value > *cond.High-margin)
+	}
+	return false
+}
+
+//SaveAlarmRule dump alarm rule in database, the previous rule of the same name is replaced
+//rather than updated to drop its optional fields
+func SaveAlarmRule(db Database, rule AlarmRule) error {
+	err := RemoveAlarmRule(db, rule.Name)
+	if err != nil {
+		return err
+	}
+	_, err = db.InsertRecord(pconst.DbConfig, AlarmRuleTable, rule)
+	return err
+}
+
+//RemoveAlarmRule remove alarm rule in database
+func RemoveAlarmRule(db Database, name string) error {
+	criteria := make(map[string]interface{})
+	criteria["Name"] = name
+	return db.DeleteRecord(pconst.DbConfig, AlarmRuleTable, criteria)
+}
+
+//GetAlarmRule return the rule of a given name or nil
+func GetAlarmRule(db Database, name string) *AlarmRule {
+	criteria := make(map[string]interface{})
+	criteria["Name"] = name
+	stored, err := db.GetRecord(pconst.DbConfig, AlarmRuleTable, criteria)
+	if err != nil || stored == nil {
+		return nil
+	}
+	rule, err := ToAlarmRule(stored)
+	if err != nil {
+		return nil
+	}
+	return rule
+}
+
+//GetAlarmRules return the alarm rules indexed by name
+func GetAlarmRules(db Database) map[string]AlarmRule {
+	rules := make(map[string]AlarmRule)
+	stored, err := db.FetchAllRecords(pconst.DbConfig, AlarmRuleTable)
+	if err != nil || stored == nil {
+		return rules
+	}
+	for _, val := range stored {
+		rule, err := ToAlarmRule(val)
+		if err != nil || rule == nil {
+			continue
+		}
+		rules[rule.Name] = *rule
+	}
+	return rules
+}
+
+//ToAlarmRule convert interface to AlarmRule object
+func ToAlarmRule(val interface{}) (*AlarmRule, error) {
+	var rule AlarmRule
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &rule)
+	return &rule, err
+}
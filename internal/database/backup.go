@@ -0,0 +1,211 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/energieip/common-components-go/pkg/dblind"
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	"github.com/energieip/common-components-go/pkg/dhvac"
+	dl "github.com/energieip/common-components-go/pkg/dled"
+	sd "github.com/energieip/common-components-go/pkg/dswitch"
+	"github.com/energieip/common-components-go/pkg/duser"
+	"github.com/energieip/common-components-go/pkg/dwago"
+	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/romana/rlog"
+)
+
+const (
+	//BackupVersion version of the configuration archive format
+	BackupVersion = 1
+)
+
+//ConfigBackup configuration archive of a switch: records of the configuration tables
+type ConfigBackup struct {
+	Version int                                 `json:"version"`
+	Date    string                              `json:"date"`
+	Mac     string                              `json:"mac"` //switch saved in the archive
+	Tables  map[string][]map[string]interface{} `json:"tables"`
+}
+
+func checkMac(mac string) error {
+	if mac == "" {
+		return errors.New("Missing mac address")
+	}
+	return nil
+}
+
+//checkBackupRecord validate a record against the content of its table
+func checkBackupRecord(tbName string, record map[string]interface{}) error {
+	if settings := getGroupSettingsTable(tbName); settings != nil {
+		cfg, err := settings.ToSettings(record)
+		if err != nil {
+			return err
+		}
+		return cfg.Check()
+	}
+	switch tbName {
+	case pconst.TbSwitchs:
+		_, err := sd.ToSwitchDefinition(record)
+		return err
+	case pconst.TbGroups:
+		_, err := gm.ToGroupConfig(record)
+		return err
+	case pconst.TbLeds:
+		cfg, err := dl.ToLedSetup(record)
+		if err != nil {
+			return err
+		}
+		return checkMac(cfg.Mac)
+	case pconst.TbSensors:
+		cfg, err := ToSensorSetup(record)
+		if err != nil {
+			return err
+		}
+		err = checkMac(cfg.Mac)
+		if err != nil {
+			return err
+		}
+		return cfg.Check()
+	case pconst.TbBlinds:
+		cfg, err := dblind.ToBlindSetup(record)
+		if err != nil {
+			return err
+		}
+		return checkMac(cfg.Mac)
+	case pconst.TbHvacs:
+		cfg, err := dhvac.ToHvacSetup(record)
+		if err != nil {
+			return err
+		}
+		return checkMac(cfg.Mac)
+	case pconst.TbWagos:
+		cfg, err := dwago.ToWagoDef(record)
+		if err != nil {
+			return err
+		}
+		return checkMac(cfg.Mac)
+	case TableCluster:
+		cfg, err := sd.ToSwitchCluster(record)
+		if err != nil {
+			return err
+		}
+		return checkMac(cfg.Mac)
+	case AccessTable:
+		cfg, err := duser.ToUserAccess(record)
+		if err != nil {
+			return err
+		}
+		if cfg.UserHash == "" {
+			return errors.New("Missing user hash")
+		}
+	case PrivilegeTable:
+		cfg, err := ToUserPrivilege(record)
+		if err != nil {
+			return err
+		}
+		if cfg.UserHash == "" {
+			return errors.New("Missing user hash")
+		}
+	case CredentialTable:
+		cfg, err := ToUserCredential(record)
+		if err != nil {
+			return err
+		}
+		if cfg.Login == "" || cfg.UserHash == "" {
+			return errors.New("Missing login or user hash")
+		}
+	case SceneTable:
+		cfg, err := ToGroupScene(record)
+		if err != nil {
+			return err
+		}
+		return cfg.Check()
+	case CalibrationTable:
+		_, err := ToGroupCalibration(record)
+		return err
+	case AlarmRuleTable:
+		cfg, err := ToAlarmRule(record)
+		if err != nil {
+			return err
+		}
+		return cfg.Check()
+	default:
+		return errors.New("Unknown table")
+	}
+	return nil
+}
+
+//Check validate the archive version and content
+func (backup ConfigBackup) Check() error {
+	if backup.Version < 1 || backup.Version > BackupVersion {
+		return errors.New("Unsupported archive version " + strconv.Itoa(backup.Version))
+	}
+	if len(backup.Tables) == 0 {
+		return errors.New("Empty archive")
+	}
+	for tbName, records := range backup.Tables {
+		for i, record := range records {
+			err := checkBackupRecord(tbName, record)
+			if err != nil {
+				return errors.New("Invalid record " + strconv.Itoa(i) + " of " + tbName + ": " + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+//ExportConfig dump the configuration tables in an archive
+func ExportConfig(db Database) ConfigBackup {
+	backup := ConfigBackup{
+		Version: BackupVersion,
+		Tables:  make(map[string][]map[string]interface{}),
+	}
+	for tbName := range configTables() {
+		records := []map[string]interface{}{}
+		stored, err := db.FetchAllRecords(pconst.DbConfig, tbName)
+		if err != nil {
+			rlog.Warn("Cannot export " + tbName + ": " + err.Error())
+		}
+		for _, val := range stored {
+			record, err := toRecord(val)
+			if err != nil {
+				continue
+			}
+			//database IDs are not kept: they are recreated on import
+			delete(record, "id")
+			records = append(records, record)
+		}
+		backup.Tables[tbName] = records
+	}
+	return backup
+}
+
+//RestoreConfig validate the archive then replace the content of the archived tables
+func RestoreConfig(db Database, backup ConfigBackup) error {
+	err := backup.Check()
+	if err != nil {
+		return err
+	}
+	tables := configTables()
+	for tbName, records := range backup.Tables {
+		obj := tables[tbName]
+		err = db.DropTable(pconst.DbConfig, tbName)
+		if err != nil {
+			rlog.Warn("Cannot drop table ", err.Error())
+		}
+		err = db.CreateTable(pconst.DbConfig, tbName, &obj)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			delete(record, "id")
+			_, err = db.InsertRecord(pconst.DbConfig, tbName, record)
+			if err != nil {
+				return err
+			}
+		}
+		rlog.Infof("Restored %v records in %v", len(records), tbName)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"errors"
+)
+
+const (
+	BlindProtectionNone  = "none"
+	BlindProtectionGlare = "glare"
+	BlindProtectionHeat  = "heat"
+
+	DefaultBlindAperture        = 90   //in degrees
+	DefaultBlindMinElevation    = 5    //in degrees
+	DefaultBlindGlareBrightness = 1000 //in lux
+	DefaultBlindGlareTimeout    = 7200 //in s
+	DefaultBlindHeatHysteresis  = 10   //in 1/10°C
+	DefaultBlindDelay           = 300  //in s
+	DefaultBlindWatchdog        = 3600 //in s
+)
+
+//GroupBlindAutomation sun protection and glare control of the group blinds
+type GroupBlindAutomation struct {
+	Group           int  `json:"group"`
+	Enabled         bool `json:"enabled"`
+	Orientation     int  `json:"orientation"`               //facade azimuth in degrees from the north, clockwise
+	Aperture        *int `json:"aperture,omitempty"`        //degrees, the sun lights the facade within orientation +/- aperture
+	MinElevation    *int `json:"minElevation,omitempty"`    //degrees, a lower sun is ignored
+	GlareBrightness *int `json:"glareBrightness,omitempty"` //lux, brightness lowering the blinds when the facade is in the sun
+	GlareSlat       *int `json:"glareSlat,omitempty"`       //slats setpoint of the glare protection
+	GlareRelease    *int `json:"glareRelease,omitempty"`    //lux, brightness releasing the glare protection without switch location
+	GlareTimeout    *int `json:"glareTimeout,omitempty"`    //s, glare protection duration before measuring again without switch location
+	HeatTemperature *int `json:"heatTemperature,omitempty"` //1/10°C, temperature lowering the blinds when the facade is in the sun, none when unset
+	HeatSlat        *int `json:"heatSlat,omitempty"`        //slats setpoint of the heat protection
+	Delay           *int `json:"delay,omitempty"`           //s, a new protection must last before moving the blinds
+	Watchdog        *int `json:"watchdog,omitempty"`        //s, back to the automatic mode after a manual command, 0 never
+}
+
+//Check validate the blind automation settings
+func (cfg GroupBlindAutomation) Check() error {
+	if cfg.Orientation < 0 || cfg.Orientation >= 360 {
+		return errors.New("Invalid orientation")
+	}
+	if cfg.Aperture != nil && (*cfg.Aperture <= 0 || *cfg.Aperture > 180) {
+		return errors.New("Invalid aperture")
+	}
+	if cfg.MinElevation != nil && (*cfg.MinElevation < 0 || *cfg.MinElevation >= 90) {
+		return errors.New("Invalid minimum elevation")
+	}
+	if cfg.GetGlareRelease() >= cfg.GetGlareBrightness() {
+		return errors.New("Invalid glare release, must be lower than the glare brightness")
+	}
+	for _, value := range []*int{cfg.GlareBrightness, cfg.GlareSlat, cfg.GlareRelease, cfg.GlareTimeout, cfg.HeatSlat, cfg.Delay, cfg.Watchdog} {
+		if value != nil && *value < 0 {
+			return errors.New("Invalid negative value")
+		}
+	}
+	return nil
+}
+
+//GetAperture return the half angle of the facade exposure in degrees
+func (cfg GroupBlindAutomation) GetAperture() int {
+	return intOrDefault(cfg.Aperture, DefaultBlindAperture)
+}
+
+//GetMinElevation return the minimum sun elevation in degrees
+func (cfg GroupBlindAutomation) GetMinElevation() int {
+	return intOrDefault(cfg.MinElevation, DefaultBlindMinElevation)
+}
+
+//GetGlareBrightness return the brightness lowering the blinds in lux
+func (cfg GroupBlindAutomation) GetGlareBrightness() int {
+	return intOrDefault(cfg.GlareBrightness, DefaultBlindGlareBrightness)
+}
+
+//GetGlareRelease return the brightness releasing the glare protection in lux, a third of the glare brightness by default
+func (cfg GroupBlindAutomation) GetGlareRelease() int {
+	return intOrDefault(cfg.GlareRelease, cfg.GetGlareBrightness()/3)
+}
+
+//GetGlareTimeout return the maximum glare protection duration without switch location in s
+func (cfg GroupBlindAutomation) GetGlareTimeout() int {
+	return intOrDefault(cfg.GlareTimeout, DefaultBlindGlareTimeout)
+}
+
+//GetDelay return the duration a new protection must last in s
+func (cfg GroupBlindAutomation) GetDelay() int {
+	return intOrDefault(cfg.Delay, DefaultBlindDelay)
+}
+
+//GetWatchdog return the duration of a manual override in s
+func (cfg GroupBlindAutomation) GetWatchdog() int {
+	return intOrDefault(cfg.Watchdog, DefaultBlindWatchdog)
+}
+
+//BlindAutomationSettings blind automation settings configuration table
+var BlindAutomationSettings = GroupSettingsTable{
+	Name:  "blind automation settings",
+	Key:   "blindAutomation",
+	Table: BlindAutomationTable,
+	New: func(grID int) GroupSettings {
+		return &GroupBlindAutomation{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the blind automation settings
+func (cfg *GroupBlindAutomation) SetGroup(grID int) {
+	cfg.Group = grID
+}
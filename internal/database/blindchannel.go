@@ -0,0 +1,72 @@
+package database
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	BlindChannelBoth = 0
+	BlindChannel1    = 1
+	BlindChannel2    = 2
+)
+
+//GroupBlindChannels blind channels belonging to a group, a blind without channel belongs to the group with both channels
+type GroupBlindChannels struct {
+	Group    int            `json:"group"`
+	Channels map[string]int `json:"channels"` //channel 1 or 2 by blind MAC
+}
+
+//BlindPosition positions of the blind channels belonging to a group
+type BlindPosition struct {
+	Blind1 *int `json:"blind1,omitempty"`
+	Slat1  *int `json:"slat1,omitempty"`
+	Blind2 *int `json:"blind2,omitempty"`
+	Slat2  *int `json:"slat2,omitempty"`
+}
+
+//Check validate the blind channels
+func (cfg GroupBlindChannels) Check() error {
+	for mac, channel := range cfg.Channels {
+		if channel != BlindChannel1 && channel != BlindChannel2 {
+			return errors.New("Invalid channel for blind " + mac)
+		}
+	}
+	return nil
+}
+
+//Channel return the channel of a blind in the group, BlindChannelBoth when none is set
+func (cfg GroupBlindChannels) Channel(mac string) int {
+	channel, ok := cfg.Channels[strings.ToUpper(mac)]
+	if !ok {
+		return BlindChannelBoth
+	}
+	return channel
+}
+
+//BlindChannelSettings blind channels configuration table
+var BlindChannelSettings = GroupSettingsTable{
+	Name:  "blind channels",
+	Key:   "blindChannels",
+	Table: BlindChannelTable,
+	New: func(grID int) GroupSettings {
+		return &GroupBlindChannels{
+			Group:    grID,
+			Channels: make(map[string]int),
+		}
+	},
+}
+
+//SetGroup set the group of the blind channels
+func (cfg *GroupBlindChannels) SetGroup(grID int) {
+	cfg.Group = grID
+}
+
+//normalize store the blinds MAC in upper case
+func (cfg *GroupBlindChannels) normalize() {
+	channels := make(map[string]int)
+	for mac, channel := range cfg.Channels {
+		channels[strings.ToUpper(mac)] = channel
+	}
+	cfg.Channels = channels
+}
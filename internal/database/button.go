@@ -0,0 +1,112 @@
+package database
+
+import (
+	"errors"
+)
+
+const (
+	ButtonPressShort  = "short"
+	ButtonPressLong   = "long" //for dimming the ramp runs until the button release
+	ButtonPressDouble = "double"
+
+	ButtonActionToggle     = "toggle"
+	ButtonActionDimUp      = "dimUp"
+	ButtonActionDimDown    = "dimDown"
+	ButtonActionBlindsUp   = "blindsUp"
+	ButtonActionBlindsDown = "blindsDown"
+	ButtonActionBlindsStop = "blindsStop"
+	ButtonActionScene      = "scene"
+	ButtonActionAuto       = "auto"
+)
+
+//ButtonAction action triggered by a button event
+type ButtonAction struct {
+	Button string `json:"button"` //A0, A1, B0 or B1 (button_A/button_B value)
+	Press  string `json:"press"`  //short, long or double
+	Action string `json:"action"`
+	Scene  string `json:"scene,omitempty"` //scene id for the scene action
+}
+
+//GroupButtons button mapping of a group
+type GroupButtons struct {
+	Group       int            `json:"group"`
+	LongPress   *int           `json:"longPress,omitempty"`   //long press duration in ms
+	DoublePress *int           `json:"doublePress,omitempty"` //max delay between two presses in ms
+	DimStep     *int           `json:"dimStep,omitempty"`     //dimming step in %
+	DimInterval *int           `json:"dimInterval,omitempty"` //dimming ramp step duration in ms
+	Actions     []ButtonAction `json:"actions"`
+}
+
+//Check validate the button action
+func (action ButtonAction) Check() error {
+	switch action.Button {
+	case "A0", "A1", "B0", "B1":
+	default:
+		return errors.New("Invalid button " + action.Button)
+	}
+	switch action.Press {
+	case ButtonPressShort, ButtonPressLong, ButtonPressDouble:
+	default:
+		return errors.New("Invalid press " + action.Press)
+	}
+	switch action.Action {
+	case ButtonActionToggle, ButtonActionDimUp, ButtonActionDimDown, ButtonActionBlindsUp,
+		ButtonActionBlindsDown, ButtonActionBlindsStop, ButtonActionAuto:
+	case ButtonActionScene:
+		if action.Scene == "" {
+			return errors.New("Missing scene for button " + action.Button)
+		}
+	default:
+		return errors.New("Invalid action " + action.Action)
+	}
+	return nil
+}
+
+//Check validate the group button mapping
+func (buttons GroupButtons) Check() error {
+	for _, value := range []*int{buttons.LongPress, buttons.DoublePress, buttons.DimStep, buttons.DimInterval} {
+		if value != nil && *value <= 0 {
+			return errors.New("Invalid button timing or step")
+		}
+	}
+	seen := make(map[string]bool)
+	for _, action := range buttons.Actions {
+		err := action.Check()
+		if err != nil {
+			return err
+		}
+		key := action.Button + "/" + action.Press
+		if _, ok := seen[key]; ok {
+			return errors.New("Duplicated " + action.Press + " press action for button " + action.Button)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+//GetAction return the action mapped on a button event or nil
+func (buttons GroupButtons) GetAction(button string, press string) *ButtonAction {
+	for _, action := range buttons.Actions {
+		if action.Button == button && action.Press == press {
+			return &action
+		}
+	}
+	return nil
+}
+
+//ButtonSettings buttons configuration table
+var ButtonSettings = GroupSettingsTable{
+	Name:  "buttons",
+	Key:   "buttons",
+	Table: ButtonTable,
+	New: func(grID int) GroupSettings {
+		return &GroupButtons{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the buttons
+func (cfg *GroupButtons) SetGroup(grID int) {
+	cfg.Group = grID
+}
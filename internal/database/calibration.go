@@ -0,0 +1,86 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+const (
+	CalibrationRunning = "running"
+	CalibrationDone    = "done"
+	CalibrationFailed  = "failed"
+)
+
+//CalibrationRequest calibration parameters
+type CalibrationRequest struct {
+	Reference    *int `json:"reference,omitempty"`    //expected brightness at 100% in lux, sensors average by default
+	StepDuration *int `json:"stepDuration,omitempty"` //time to wait for each setpoint in s
+}
+
+//CalibrationPoint raw sensor brightness measured for a LED setpoint
+type CalibrationPoint struct {
+	Setpoint   int `json:"setpoint"`
+	Brightness int `json:"brightness"`
+}
+
+//SensorCalibration calibration result of a sensor
+type SensorCalibration struct {
+	Mac        string             `json:"mac"`
+	Points     []CalibrationPoint `json:"points"`
+	Slope      float64            `json:"slope"`      //LED contribution in raw lux per %
+	Intercept  float64            `json:"intercept"`  //raw brightness with LEDs off
+	FitQuality float64            `json:"fitQuality"` //coefficient of determination (R²)
+	Factor     float32            `json:"factor"`
+	Applied    bool               `json:"applied"` //factor stored in the sensor configuration
+}
+
+//GroupCalibration last calibration of a group
+type GroupCalibration struct {
+	Group     int                          `json:"group"`
+	Status    string                       `json:"status"`
+	Date      string                       `json:"date"`
+	Reference int                          `json:"reference"`
+	Error     string                       `json:"error,omitempty"`
+	Sensors   map[string]SensorCalibration `json:"sensors"`
+}
+
+//SaveGroupCalibration dump group calibration in database
+func SaveGroupCalibration(db Database, cfg GroupCalibration) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = cfg.Group
+	return SaveOnUpdateObject(db, cfg, pconst.DbConfig, CalibrationTable, criteria)
+}
+
+//RemoveGroupCalibration remove group calibration in database
+func RemoveGroupCalibration(db Database, grID int) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	return db.DeleteRecord(pconst.DbConfig, CalibrationTable, criteria)
+}
+
+//GetGroupCalibration return the last calibration of a given group or nil
+func GetGroupCalibration(db Database, grID int) *GroupCalibration {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	stored, err := db.GetRecord(pconst.DbConfig, CalibrationTable, criteria)
+	if err != nil || stored == nil {
+		return nil
+	}
+	cfg, err := ToGroupCalibration(stored)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+//ToGroupCalibration convert interface to GroupCalibration object
+func ToGroupCalibration(val interface{}) (*GroupCalibration, error) {
+	var cfg GroupCalibration
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &cfg)
+	return &cfg, err
+}
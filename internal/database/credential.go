@@ -0,0 +1,56 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+//UserCredential local login of a user of the access table, the password is stored as a salted PBKDF2 key
+type UserCredential struct {
+	Login      string `json:"login"`
+	UserHash   string `json:"userHash"`
+	Salt       string `json:"salt"` //hex encoded
+	Key        string `json:"key"`  //hex encoded
+	Iterations int    `json:"iterations"`
+}
+
+//SaveUserCredential dump user credential in database
+func SaveUserCredential(db Database, cfg UserCredential) error {
+	criteria := make(map[string]interface{})
+	criteria["Login"] = cfg.Login
+	return SaveOnUpdateObject(db, cfg, pconst.DbConfig, CredentialTable, criteria)
+}
+
+//RemoveUserCredentials remove the logins of a given user in database
+func RemoveUserCredentials(db Database, userHash string) error {
+	criteria := make(map[string]interface{})
+	criteria["UserHash"] = userHash
+	return db.DeleteRecord(pconst.DbConfig, CredentialTable, criteria)
+}
+
+//GetUserCredential return the credential of a given login or nil if none is set
+func GetUserCredential(db Database, login string) *UserCredential {
+	criteria := make(map[string]interface{})
+	criteria["Login"] = login
+	stored, err := db.GetRecord(pconst.DbConfig, CredentialTable, criteria)
+	if err != nil || stored == nil {
+		return nil
+	}
+	cred, err := ToUserCredential(stored)
+	if err != nil {
+		return nil
+	}
+	return cred
+}
+
+//ToUserCredential convert interface to UserCredential object
+func ToUserCredential(val interface{}) (*UserCredential, error) {
+	var cred UserCredential
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &cred)
+	return &cred, err
+}
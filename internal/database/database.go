@@ -15,16 +15,46 @@ import (
 	"github.com/romana/rlog"
 )
 
-type Database = database.DatabaseInterface
+//Database storage operations used by the firmware
+type Database interface {
+	Initialize(confDb database.DatabaseConfig) error
+	CreateDB(dbName string) error
+	CreateTable(dbName, tableName string, obj interface{}) error
+	DropTable(dbName, tableName string) error
+	GetRecord(dbName, tbName string, criteria map[string]interface{}) (interface{}, error)
+	FetchAllRecords(dbName, tbName string) ([]interface{}, error)
+	InsertRecord(dbName, tbName string, obj interface{}) (string, error)
+	UpdateRecord(dbName, tbName, id string, obj interface{}) error
+	DeleteRecord(dbName, tbName string, obj interface{}) error
+	Close() error
+}
 
 const (
-	TableCluster = "clusters"
-	AccessTable  = "access"
+	TableCluster         = "clusters"
+	AccessTable          = "access"
+	PrivilegeTable       = "privileges"
+	CredentialTable      = "credentials"
+	ScheduleTable        = "schedules"
+	SceneTable           = "scenes"
+	ButtonTable          = "buttons"
+	DaylightTable        = "daylight"
+	CalibrationTable     = "calibrations"
+	AlarmRuleTable       = "alarmRules"
+	WindowInterlockTable = "windowInterlock"
+	HvacOccupancyTable   = "hvacOccupancy"
+	BlindAutomationTable = "blindAutomation"
+	BlindChannelTable    = "blindChannels"
+	ThermalControlTable  = "thermalControl"
+	AirQualityTable      = "airQuality"
+	SensorRulesTable     = "sensorRules"
+	RuntimeTable         = "runtime"
+	EnergyTable          = "energy"
+	JournalTable         = "journal"
 )
 
-//ConnectDatabase
-func ConnectDatabase(ip, port string) (Database, error) {
-	db, err := database.NewDatabase(database.RETHINKDB)
+//ConnectDatabase open the storage backend selected in the configuration
+func ConnectDatabase(storage StorageConfig, ip, port string) (Database, error) {
+	db, err := newStorage(storage)
 	if err != nil {
 		rlog.Error("database err " + err.Error())
 		return nil, err
@@ -43,6 +73,45 @@ func ConnectDatabase(ip, port string) (Database, error) {
 	return db, nil
 }
 
+//configTables tables of the configuration database and their content
+func configTables() map[string]interface{} {
+	tableCfg := make(map[string]interface{})
+	tableCfg[pconst.TbLeds] = dl.LedSetup{}
+	tableCfg[pconst.TbSensors] = ds.SensorSetup{}
+	tableCfg[pconst.TbGroups] = gm.GroupConfig{}
+	tableCfg[pconst.TbBlinds] = dblind.BlindSetup{}
+	tableCfg[pconst.TbWagos] = dwago.WagoDef{}
+	tableCfg[TableCluster] = pkg.Broker{}
+	tableCfg[pconst.TbHvacs] = dhvac.HvacSetup{}
+	tableCfg[AccessTable] = duser.UserAccess{}
+	tableCfg[PrivilegeTable] = UserPrivilege{}
+	tableCfg[CredentialTable] = UserCredential{}
+	tableCfg[ScheduleTable] = GroupSchedule{}
+	tableCfg[SceneTable] = GroupScene{}
+	tableCfg[ButtonTable] = GroupButtons{}
+	tableCfg[DaylightTable] = GroupDaylight{}
+	tableCfg[CalibrationTable] = GroupCalibration{}
+	tableCfg[AlarmRuleTable] = AlarmRule{}
+	tableCfg[WindowInterlockTable] = GroupWindowInterlock{}
+	tableCfg[HvacOccupancyTable] = GroupHvacOccupancy{}
+	tableCfg[BlindAutomationTable] = GroupBlindAutomation{}
+	tableCfg[BlindChannelTable] = GroupBlindChannels{}
+	tableCfg[ThermalControlTable] = GroupThermalControl{}
+	tableCfg[AirQualityTable] = GroupAirQuality{}
+	tableCfg[SensorRulesTable] = GroupSensorRules{}
+	tableCfg[pconst.TbSwitchs] = sd.SwitchDefinition{}
+	return tableCfg
+}
+
+//statusTables tables of the status database and their content
+func statusTables() map[string]interface{} {
+	tableCfg := make(map[string]interface{})
+	tableCfg[RuntimeTable] = GroupRuntimeState{}
+	tableCfg[EnergyTable] = EnergyBucket{}
+	tableCfg[JournalTable] = JournalEntry{}
+	return tableCfg
+}
+
 func prepareDB(db Database, withDrop bool) {
 	for _, dbName := range []string{pconst.DbConfig, pconst.DbStatus} {
 		err := db.CreateDB(dbName)
@@ -52,15 +121,9 @@ func prepareDB(db Database, withDrop bool) {
 
 		tableCfg := make(map[string]interface{})
 		if dbName == pconst.DbConfig {
-			tableCfg[pconst.TbLeds] = dl.LedSetup{}
-			tableCfg[pconst.TbSensors] = ds.SensorSetup{}
-			tableCfg[pconst.TbGroups] = gm.GroupConfig{}
-			tableCfg[pconst.TbBlinds] = dblind.BlindSetup{}
-			tableCfg[pconst.TbWagos] = dwago.WagoDef{}
-			tableCfg[TableCluster] = pkg.Broker{}
-			tableCfg[pconst.TbHvacs] = dhvac.HvacSetup{}
-			tableCfg[AccessTable] = duser.UserAccess{}
-			tableCfg[pconst.TbSwitchs] = sd.SwitchDefinition{}
+			tableCfg = configTables()
+		} else {
+			tableCfg = statusTables()
 		}
 		for tableName, objs := range tableCfg {
 			if withDrop {
@@ -79,6 +142,11 @@ func prepareDB(db Database, withDrop bool) {
 
 }
 
+//PrepareDB create the firmware databases and tables if needed
+func PrepareDB(db Database) {
+	prepareDB(db, false)
+}
+
 func ResetDB(db Database) error {
 	prepareDB(db, true)
 	var res error
@@ -101,6 +169,32 @@ func GetObjectID(db Database, dbName, tbName string, criteria map[string]interfa
 	return ""
 }
 
+//recordsDeleter storage able to remove several records in a single write
+type recordsDeleter interface {
+	DeleteRecords(dbName, tbName string, ids []string) error
+}
+
+//DeleteRecords remove the records with the given ids
+func DeleteRecords(db Database, dbName, tbName string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if deleter, ok := db.(recordsDeleter); ok {
+		return deleter.DeleteRecords(dbName, tbName, ids)
+	}
+	var res error
+	for _, id := range ids {
+		criteria := make(map[string]interface{})
+		criteria["id"] = id
+		err := db.DeleteRecord(dbName, tbName, criteria)
+		if err != nil {
+			//best effort
+			res = err
+		}
+	}
+	return res
+}
+
 //SaveOnUpdateObject in database
 func SaveOnUpdateObject(db Database, obj interface{}, dbName, tbName string, criteria map[string]interface{}) error {
 	var err error
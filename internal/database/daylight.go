@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+)
+
+const (
+	DaylightControllerStep = "step" //fixed scale correction (default)
+	DaylightControllerPI   = "pi"   //proportional-integral controller
+
+	DefaultDaylightKp       = 0.02  //in %/lux
+	DefaultDaylightKi       = 0.004 //in %/(lux.s)
+	DefaultDaylightDeadBand = 20    //in lux
+)
+
+//GroupDaylight daylight harvesting controller settings of a group
+type GroupDaylight struct {
+	Group      int      `json:"group"`
+	Controller string   `json:"controller"`
+	Kp         *float64 `json:"kp,omitempty"`       //proportional gain in %/lux
+	Ki         *float64 `json:"ki,omitempty"`       //integral gain in %/(lux.s)
+	DeadBand   *int     `json:"deadBand,omitempty"` //no correction when the brightness error is within the dead band (lux)
+}
+
+//Check validate the daylight settings
+func (cfg GroupDaylight) Check() error {
+	switch cfg.Controller {
+	case DaylightControllerStep, DaylightControllerPI:
+	default:
+		return errors.New("Invalid controller " + cfg.Controller)
+	}
+	if cfg.Kp != nil && *cfg.Kp < 0 {
+		return errors.New("Invalid negative kp")
+	}
+	if cfg.Ki != nil && *cfg.Ki < 0 {
+		return errors.New("Invalid negative ki")
+	}
+	if cfg.DeadBand != nil && *cfg.DeadBand < 0 {
+		return errors.New("Invalid negative dead band")
+	}
+	return nil
+}
+
+//GetKp return the proportional gain
+func (cfg GroupDaylight) GetKp() float64 {
+	if cfg.Kp != nil {
+		return *cfg.Kp
+	}
+	return DefaultDaylightKp
+}
+
+//GetKi return the integral gain
+func (cfg GroupDaylight) GetKi() float64 {
+	if cfg.Ki != nil {
+		return *cfg.Ki
+	}
+	return DefaultDaylightKi
+}
+
+//GetDeadBand return the dead band
+func (cfg GroupDaylight) GetDeadBand() int {
+	if cfg.DeadBand != nil {
+		return *cfg.DeadBand
+	}
+	return DefaultDaylightDeadBand
+}
+
+//DaylightSettings daylight settings configuration table
+var DaylightSettings = GroupSettingsTable{
+	Name:  "daylight settings",
+	Key:   "daylight",
+	Table: DaylightTable,
+	New: func(grID int) GroupSettings {
+		return &GroupDaylight{
+			Group:      grID,
+			Controller: DaylightControllerStep,
+		}
+	},
+}
+
+//SetGroup set the group of the daylight settings
+func (cfg *GroupDaylight) SetGroup(grID int) {
+	cfg.Group = grID
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/romana/rlog"
+)
+
+const (
+	EnergyQuarter = "quarter"
+	EnergyHour    = "hour"
+	EnergyDay     = "day"
+
+	EnergyLighting = "lighting"
+	EnergyBlinds   = "blinds"
+	EnergyHvacs    = "hvacs"
+)
+
+//EnergyPeriods aggregation periods of the energy buckets and their duration
+var EnergyPeriods = map[string]time.Duration{
+	EnergyQuarter: 15 * time.Minute,
+	EnergyHour:    time.Hour,
+	EnergyDay:     24 * time.Hour,
+}
+
+//EnergyCounters energy consumed by category, in Wh
+type EnergyCounters struct {
+	Total    float64 `json:"total"`
+	Lighting float64 `json:"lighting"`
+	Blinds   float64 `json:"blinds"`
+	Hvacs    float64 `json:"hvacs"`
+}
+
+//DriverEnergy energy consumed by a driver, in Wh
+type DriverEnergy struct {
+	Group    int     `json:"group"`
+	Category string  `json:"category"` //lighting, blinds or hvacs
+	Energy   float64 `json:"energy"`
+}
+
+//EnergyBucket energy consumed during a quarter, an hour or a day (UTC)
+type EnergyBucket struct {
+	Period string `json:"period"` //quarter, hour or day
+	Start  string `json:"start"`  //RFC3339 UTC date of the period start
+	EnergyCounters
+	Groups  map[int]EnergyCounters  `json:"groups"`
+	Drivers map[string]DriverEnergy `json:"drivers"`
+}
+
+//NewEnergyBucket create an empty bucket for the period containing the given date
+func NewEnergyBucket(period string, date time.Time) EnergyBucket {
+	return EnergyBucket{
+		Period:  period,
+		Start:   EnergyPeriodStart(period, date).Format(time.RFC3339),
+		Groups:  make(map[int]EnergyCounters),
+		Drivers: make(map[string]DriverEnergy),
+	}
+}
+
+//EnergyPeriodStart return the UTC start of the period containing the given date
+func EnergyPeriodStart(period string, date time.Time) time.Time {
+	return date.UTC().Truncate(EnergyPeriods[period])
+}
+
+func (counters *EnergyCounters) add(category string, energy float64) {
+	counters.Total += energy
+	switch category {
+	case EnergyLighting:
+		counters.Lighting += energy
+	case EnergyBlinds:
+		counters.Blinds += energy
+	case EnergyHvacs:
+		counters.Hvacs += energy
+	}
+}
+
+//Add count the energy consumed by a driver
+func (bucket *EnergyBucket) Add(mac string, group int, category string, energy float64) {
+	bucket.EnergyCounters.add(category, energy)
+	counters := bucket.Groups[group]
+	counters.add(category, energy)
+	bucket.Groups[group] = counters
+	driver := bucket.Drivers[mac]
+	driver.Group = group
+	driver.Category = category
+	driver.Energy += energy
+	bucket.Drivers[mac] = driver
+}
+
+//Filter return a copy of the bucket restricted to the accepted groups
+func (bucket EnergyBucket) Filter(accept func(grID int) bool) EnergyBucket {
+	res := EnergyBucket{
+		Period:  bucket.Period,
+		Start:   bucket.Start,
+		Groups:  make(map[int]EnergyCounters),
+		Drivers: make(map[string]DriverEnergy),
+	}
+	for grID, counters := range bucket.Groups {
+		if !accept(grID) {
+			continue
+		}
+		res.Groups[grID] = counters
+		res.Total += counters.Total
+		res.Lighting += counters.Lighting
+		res.Blinds += counters.Blinds
+		res.Hvacs += counters.Hvacs
+	}
+	for mac, driver := range bucket.Drivers {
+		if accept(driver.Group) {
+			res.Drivers[mac] = driver
+		}
+	}
+	return res
+}
+
+func energyCriteria(period, start string) map[string]interface{} {
+	criteria := make(map[string]interface{})
+	criteria["Period"] = period
+	criteria["Start"] = start
+	return criteria
+}
+
+//SaveEnergyBucket dump energy bucket in database
+func SaveEnergyBucket(db Database, bucket EnergyBucket) error {
+	return SaveOnUpdateObject(db, bucket, pconst.DbStatus, EnergyTable, energyCriteria(bucket.Period, bucket.Start))
+}
+
+//GetEnergyBucket return the stored bucket of a period or nil
+func GetEnergyBucket(db Database, period, start string) *EnergyBucket {
+	stored, err := db.GetRecord(pconst.DbStatus, EnergyTable, energyCriteria(period, start))
+	if err != nil || stored == nil {
+		return nil
+	}
+	bucket, err := ToEnergyBucket(stored)
+	if err != nil {
+		return nil
+	}
+	return bucket
+}
+
+//GetEnergyBuckets return the stored buckets of a period starting between from and to
+func GetEnergyBuckets(db Database, period string, from, to time.Time) []EnergyBucket {
+	var buckets []EnergyBucket
+	stored, err := db.FetchAllRecords(pconst.DbStatus, EnergyTable)
+	if err != nil || stored == nil {
+		return buckets
+	}
+	for _, val := range stored {
+		bucket, err := ToEnergyBucket(val)
+		if err != nil || bucket.Period != period {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, bucket.Start)
+		if err != nil || start.Before(from) || start.After(to) {
+			continue
+		}
+		buckets = append(buckets, *bucket)
+	}
+	return buckets
+}
+
+//RemoveEnergyBuckets remove the buckets of a period started before the given date
+func RemoveEnergyBuckets(db Database, period string, before time.Time) {
+	stored, err := db.FetchAllRecords(pconst.DbStatus, EnergyTable)
+	if err != nil || stored == nil {
+		return
+	}
+	var removed []string
+	for _, val := range stored {
+		bucket, err := ToEnergyBucket(val)
+		if err != nil || bucket.Period != period {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, bucket.Start)
+		if err != nil || !start.Before(before) {
+			continue
+		}
+		if record, ok := val.(map[string]interface{}); ok {
+			if id, ok := record["id"].(string); ok {
+				removed = append(removed, id)
+			}
+		}
+	}
+	err = DeleteRecords(db, pconst.DbStatus, EnergyTable, removed)
+	if err != nil {
+		rlog.Warn("Cannot remove " + period + " energy buckets: " + err.Error())
+	}
+}
+
+//ToEnergyBucket convert interface to EnergyBucket object
+func ToEnergyBucket(val interface{}) (*EnergyBucket, error) {
+	var bucket EnergyBucket
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &bucket)
+	if bucket.Groups == nil {
+		bucket.Groups = make(map[int]EnergyCounters)
+	}
+	if bucket.Drivers == nil {
+		bucket.Drivers = make(map[string]DriverEnergy)
+	}
+	return &bucket, err
+}
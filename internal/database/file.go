@@ -0,0 +1,237 @@
+package database
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/energieip/common-components-go/pkg/database"
+	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/romana/rlog"
+)
+
+//FileDatabase embedded storage: the records are served from memory and the
+//configuration database is written in a JSON file on each change.
+//The status database is rebuilt by the drivers and kept in memory only to spare the flash,
+//except the group runtime states, the energy buckets and the drivers journal. These tables
+//change at each status dump: they are written in a separate file at most every statusSaveDelay.
+type FileDatabase struct {
+	*MemoryDatabase
+	path        string
+	statusPath  string
+	mutex       sync.Mutex
+	statusMutex sync.Mutex
+	statusTimer *time.Timer //pending status write, nil when the status file is up to date
+}
+
+const statusSaveDelay = time.Minute
+
+//NewFileDatabase create a file backed database stored in the given path
+func NewFileDatabase(path string) *FileDatabase {
+	return &FileDatabase{
+		MemoryDatabase: NewMemoryDatabase(),
+		path:           path,
+		statusPath:     strings.TrimSuffix(path, filepath.Ext(path)) + ".status" + filepath.Ext(path),
+	}
+}
+
+func isStatusPersistent(dbName, tbName string) bool {
+	if dbName != pconst.DbStatus {
+		return false
+	}
+	switch tbName {
+	case RuntimeTable, EnergyTable, JournalTable:
+		return true
+	}
+	return false
+}
+
+func isConfigPersistent(dbName, tbName string) bool {
+	return dbName == pconst.DbConfig
+}
+
+//load read a stored file in the memory databases
+func (f *FileDatabase) load(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	dbs := make(map[string]map[string][]map[string]interface{})
+	err = json.Unmarshal(content, &dbs)
+	if err != nil {
+		return err
+	}
+	f.MemoryDatabase.mutex.Lock()
+	defer f.MemoryDatabase.mutex.Unlock()
+	for dbName, tables := range dbs {
+		if _, ok := f.MemoryDatabase.dbs[dbName]; !ok {
+			f.MemoryDatabase.dbs[dbName] = make(map[string][]map[string]interface{})
+		}
+		for tbName, table := range tables {
+			for _, record := range table {
+				id, _ := record["id"].(string)
+				val, err := strconv.Atoi(id)
+				if err == nil && val > f.MemoryDatabase.nextID {
+					f.MemoryDatabase.nextID = val
+				}
+			}
+			f.MemoryDatabase.dbs[dbName][tbName] = table
+		}
+	}
+	return nil
+}
+
+//Initialize load the stored configuration and status
+func (f *FileDatabase) Initialize(confDb database.DatabaseConfig) error {
+	err := os.MkdirAll(filepath.Dir(f.path), 0755)
+	if err != nil {
+		return err
+	}
+	//the status tables of the former single file are overridden by the status file
+	err = f.load(f.path)
+	if err != nil {
+		return err
+	}
+	return f.load(f.statusPath)
+}
+
+//write dump the selected tables in a temporary file then replace the stored one
+func (f *FileDatabase) write(path string, persistent func(dbName, tbName string) bool) error {
+	dbs := make(map[string]map[string][]map[string]interface{})
+	f.MemoryDatabase.mutex.RLock()
+	for name, tables := range f.MemoryDatabase.dbs {
+		for table, records := range tables {
+			if !persistent(name, table) {
+				continue
+			}
+			if _, ok := dbs[name]; !ok {
+				dbs[name] = make(map[string][]map[string]interface{})
+			}
+			dbs[name][table] = records
+		}
+	}
+	content, err := json.Marshal(dbs)
+	f.MemoryDatabase.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+//saveStatus write the status file
+func (f *FileDatabase) saveStatus() {
+	f.statusMutex.Lock()
+	f.statusTimer = nil
+	f.statusMutex.Unlock()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	err := f.write(f.statusPath, isStatusPersistent)
+	if err != nil {
+		rlog.Error("Cannot save status database " + err.Error())
+	}
+}
+
+//save write the configuration file on each change and schedule the write of the status file
+func (f *FileDatabase) save(dbName, tbName string) error {
+	if isStatusPersistent(dbName, tbName) {
+		f.statusMutex.Lock()
+		if f.statusTimer == nil {
+			f.statusTimer = time.AfterFunc(statusSaveDelay, f.saveStatus)
+		}
+		f.statusMutex.Unlock()
+		return nil
+	}
+	if !isConfigPersistent(dbName, tbName) {
+		return nil
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.write(f.path, isConfigPersistent)
+}
+
+//Close write the pending status changes
+func (f *FileDatabase) Close() error {
+	f.statusMutex.Lock()
+	pending := f.statusTimer != nil && f.statusTimer.Stop()
+	f.statusMutex.Unlock()
+	if pending {
+		f.saveStatus()
+	}
+	return f.MemoryDatabase.Close()
+}
+
+//CreateDB create a database
+func (f *FileDatabase) CreateDB(dbName string) error {
+	err := f.MemoryDatabase.CreateDB(dbName)
+	if err != nil {
+		return err
+	}
+	return f.save(dbName, "")
+}
+
+//CreateTable create a table
+func (f *FileDatabase) CreateTable(dbName, tableName string, obj interface{}) error {
+	err := f.MemoryDatabase.CreateTable(dbName, tableName, obj)
+	if err != nil {
+		return err
+	}
+	return f.save(dbName, tableName)
+}
+
+//DropTable remove a table and its records
+func (f *FileDatabase) DropTable(dbName, tableName string) error {
+	err := f.MemoryDatabase.DropTable(dbName, tableName)
+	if err != nil {
+		return err
+	}
+	return f.save(dbName, tableName)
+}
+
+//InsertRecord add a record and return its id
+func (f *FileDatabase) InsertRecord(dbName, tbName string, obj interface{}) (string, error) {
+	id, err := f.MemoryDatabase.InsertRecord(dbName, tbName, obj)
+	if err != nil {
+		return id, err
+	}
+	return id, f.save(dbName, tbName)
+}
+
+//UpdateRecord merge the object fields in the record with the given id
+func (f *FileDatabase) UpdateRecord(dbName, tbName, id string, obj interface{}) error {
+	err := f.MemoryDatabase.UpdateRecord(dbName, tbName, id, obj)
+	if err != nil {
+		return err
+	}
+	return f.save(dbName, tbName)
+}
+
+//DeleteRecord remove the records matching the object fields
+func (f *FileDatabase) DeleteRecord(dbName, tbName string, obj interface{}) error {
+	err := f.MemoryDatabase.DeleteRecord(dbName, tbName, obj)
+	if err != nil {
+		return err
+	}
+	return f.save(dbName, tbName)
+}
+
+//DeleteRecords remove the records with the given ids
+func (f *FileDatabase) DeleteRecords(dbName, tbName string, ids []string) error {
+	err := f.MemoryDatabase.DeleteRecords(dbName, tbName, ids)
+	if err != nil {
+		return err
+	}
+	return f.save(dbName, tbName)
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+//GroupSettings settings of a group stored in a per group configuration table
+type GroupSettings interface {
+	SetGroup(grID int)
+	Check() error
+}
+
+//settingsNormalizer settings rewritten before being stored
+type settingsNormalizer interface {
+	normalize()
+}
+
+//GroupSettingsTable per group configuration table
+type GroupSettingsTable struct {
+	Name  string                       //settings name in the logs and errors
+	Key   string                       //settings key in the server messages
+	Table string                       //configuration table
+	New   func(grID int) GroupSettings //default settings of a group
+}
+
+//GroupSettingsTables per group configuration tables, removed with the group
+func GroupSettingsTables() []GroupSettingsTable {
+	return []GroupSettingsTable{
+		ScheduleSettings,
+		ButtonSettings,
+		DaylightSettings,
+		WindowInterlockSettings,
+		HvacOccupancySettings,
+		BlindAutomationSettings,
+		BlindChannelSettings,
+		ThermalControlSettings,
+		AirQualitySettings,
+		SensorRuleSettings,
+	}
+}
+
+//getGroupSettingsTable return the per group configuration table of the given name or nil
+func getGroupSettingsTable(tbName string) *GroupSettingsTable {
+	for _, table := range GroupSettingsTables() {
+		if table.Table == tbName {
+			return &table
+		}
+	}
+	return nil
+}
+
+//SaveGroupSettings dump the settings of a group in database
+func SaveGroupSettings(db Database, table GroupSettingsTable, grID int, cfg GroupSettings) error {
+	cfg.SetGroup(grID)
+	if normalizer, ok := cfg.(settingsNormalizer); ok {
+		normalizer.normalize()
+	}
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	return SaveOnUpdateObject(db, cfg, pconst.DbConfig, table.Table, criteria)
+}
+
+//RemoveGroupSettings remove the settings of a group in database
+func RemoveGroupSettings(db Database, table GroupSettingsTable, grID int) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	return db.DeleteRecord(pconst.DbConfig, table.Table, criteria)
+}
+
+//GetGroupSettings return the settings of a given group or nil if none is set
+func GetGroupSettings(db Database, table GroupSettingsTable, grID int) GroupSettings {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	stored, err := db.GetRecord(pconst.DbConfig, table.Table, criteria)
+	if err != nil || stored == nil {
+		return nil
+	}
+	cfg, err := table.ToSettings(stored)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+//ToSettings convert interface to the settings object of the table
+func (table GroupSettingsTable) ToSettings(val interface{}) (GroupSettings, error) {
+	cfg := table.New(0)
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, cfg)
+	return cfg, err
+}
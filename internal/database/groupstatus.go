@@ -0,0 +1,26 @@
+package database
+
+import (
+	"encoding/json"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+)
+
+//GroupStatus group status extended with the switch side automations
+type GroupStatus struct {
+	gm.GroupStatus
+	BlindsPositions map[string]BlindPosition `json:"blindsPositions,omitempty"` //by blind MAC
+	ThermalDemand   *int                     `json:"thermalDemand,omitempty"`   //switch side thermal control demand in %, positive when heating
+	AirQualityIndex *int                     `json:"airQualityIndex,omitempty"` //good, moderate or poor when the air quality rules are enabled
+}
+
+//ToGroupStatus convert interface to GroupStatus object
+func ToGroupStatus(val interface{}) (*GroupStatus, error) {
+	var status GroupStatus
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &status)
+	return &status, err
+}
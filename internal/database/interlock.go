@@ -0,0 +1,63 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/energieip/common-components-go/pkg/dhvac"
+)
+
+const (
+	DefaultInterlockDelay = 60 //in s
+)
+
+//GroupWindowInterlock HVAC protection of a group while a window stays opened
+type GroupWindowInterlock struct {
+	Group      int  `json:"group"`
+	Enabled    bool `json:"enabled"`
+	Delay      *int `json:"delay,omitempty"`      //s, the window must stay opened before forcing the HVACs
+	TargetMode *int `json:"targetMode,omitempty"` //HVAC target mode forced while the window is opened
+}
+
+//Check validate the window interlock settings
+func (cfg GroupWindowInterlock) Check() error {
+	if cfg.Delay != nil && *cfg.Delay < 0 {
+		return errors.New("Invalid negative delay")
+	}
+	if cfg.TargetMode != nil && *cfg.TargetMode < 0 {
+		return errors.New("Invalid negative target mode")
+	}
+	return nil
+}
+
+//GetDelay return the delay before forcing the HVACs in s
+func (cfg GroupWindowInterlock) GetDelay() int {
+	if cfg.Delay != nil {
+		return *cfg.Delay
+	}
+	return DefaultInterlockDelay
+}
+
+//GetTargetMode return the HVAC target mode forced while the window is opened
+func (cfg GroupWindowInterlock) GetTargetMode() int {
+	if cfg.TargetMode != nil {
+		return *cfg.TargetMode
+	}
+	return dhvac.OCCUPANCY_STANDBY
+}
+
+//WindowInterlockSettings window interlock settings configuration table
+var WindowInterlockSettings = GroupSettingsTable{
+	Name:  "window interlock settings",
+	Key:   "windowInterlock",
+	Table: WindowInterlockTable,
+	New: func(grID int) GroupSettings {
+		return &GroupWindowInterlock{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the window interlock settings
+func (cfg *GroupWindowInterlock) SetGroup(grID int) {
+	cfg.Group = grID
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/romana/rlog"
+)
+
+const (
+	JournalHello      = "hello"
+	JournalConfigured = "configured"
+	JournalError      = "error"
+	JournalRecovered  = "recovered"
+	JournalLost       = "lost"
+	JournalRemoved    = "removed"
+	JournalReset      = "reset"
+	JournalGroup      = "group"
+)
+
+//JournalEntry driver lifecycle event
+type JournalEntry struct {
+	Date       string `json:"date"` //RFC3339 UTC date of the event
+	Mac        string `json:"mac"`
+	DriverType string `json:"driverType"`
+	Event      string `json:"event"`
+	Group      *int   `json:"group,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+//JournalFilter criteria of a journal query, empty values match all the entries
+type JournalFilter struct {
+	Mac        string
+	DriverType string
+	Event      string
+	Group      *int
+	From       time.Time
+	To         time.Time
+	Limit      int //maximum number of the most recent entries, 0 for all
+}
+
+//match check if an entry fulfills the filter
+func (filter JournalFilter) match(entry JournalEntry, date time.Time) bool {
+	if filter.Mac != "" && filter.Mac != entry.Mac {
+		return false
+	}
+	if filter.DriverType != "" && filter.DriverType != entry.DriverType {
+		return false
+	}
+	if filter.Event != "" && filter.Event != entry.Event {
+		return false
+	}
+	if filter.Group != nil && (entry.Group == nil || *entry.Group != *filter.Group) {
+		return false
+	}
+	if !filter.From.IsZero() && date.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && date.After(filter.To) {
+		return false
+	}
+	return true
+}
+
+//SaveJournalEntry add an event in the journal
+func SaveJournalEntry(db Database, entry JournalEntry) error {
+	_, err := db.InsertRecord(pconst.DbStatus, JournalTable, entry)
+	return err
+}
+
+//getJournal return the journal entries sorted by date with their database ID
+func getJournal(db Database) ([]JournalEntry, []string) {
+	var entries []JournalEntry
+	var ids []string
+	stored, err := db.FetchAllRecords(pconst.DbStatus, JournalTable)
+	if err != nil || stored == nil {
+		return entries, ids
+	}
+	type record struct {
+		JournalEntry
+		ID string `json:"id"`
+	}
+	var records []record
+	for _, val := range stored {
+		var rec record
+		inrec, err := json.Marshal(val)
+		if err != nil {
+			continue
+		}
+		err = json.Unmarshal(inrec, &rec)
+		if err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Date < records[j].Date })
+	for _, rec := range records {
+		entries = append(entries, rec.JournalEntry)
+		ids = append(ids, rec.ID)
+	}
+	return entries, ids
+}
+
+//GetJournalEntries return the journal entries matching the filter, sorted by date
+func GetJournalEntries(db Database, filter JournalFilter) []JournalEntry {
+	entries, _ := getJournal(db)
+	res := []JournalEntry{}
+	for _, entry := range entries {
+		date, err := time.Parse(time.RFC3339, entry.Date)
+		if err != nil || !filter.match(entry, date) {
+			continue
+		}
+		res = append(res, entry)
+	}
+	if filter.Limit > 0 && len(res) > filter.Limit {
+		res = res[len(res)-filter.Limit:]
+	}
+	return res
+}
+
+//PurgeJournal remove the entries older than the given date and the oldest ones above maxEntries
+//return the number of entries kept
+func PurgeJournal(db Database, before time.Time, maxEntries int) int {
+	entries, ids := getJournal(db)
+	var removed []string
+	for i, entry := range entries {
+		date, err := time.Parse(time.RFC3339, entry.Date)
+		if err == nil && !date.Before(before) && len(entries)-i <= maxEntries {
+			//sorted by date: the following entries are kept
+			break
+		}
+		removed = append(removed, ids[i])
+	}
+	err := DeleteRecords(db, pconst.DbStatus, JournalTable, removed)
+	if err != nil {
+		rlog.Warn("Cannot remove journal entries: " + err.Error())
+	}
+	return len(entries) - len(removed)
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/energieip/common-components-go/pkg/database"
+)
+
+//MemoryDatabase in-memory storage with the RethinkDB record semantics:
+//records are JSON documents identified by an "id" field and filtered by field values
+type MemoryDatabase struct {
+	mutex  sync.RWMutex
+	dbs    map[string]map[string][]map[string]interface{}
+	nextID int
+}
+
+//NewMemoryDatabase create an empty in-memory database
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{
+		dbs: make(map[string]map[string][]map[string]interface{}),
+	}
+}
+
+//toRecord convert an object in a generic JSON document
+func toRecord(obj interface{}) (map[string]interface{}, error) {
+	record := make(map[string]interface{})
+	dump, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(dump, &record)
+	return record, err
+}
+
+//getField return a record field; keys are compared case-insensitively since
+//the criteria use the Go field names and the records the JSON ones
+func getField(record map[string]interface{}, key string) (interface{}, bool) {
+	if val, ok := record[key]; ok {
+		return val, true
+	}
+	for k, val := range record {
+		if strings.EqualFold(k, key) {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+func matchRecord(record map[string]interface{}, filter map[string]interface{}) bool {
+	for key, expected := range filter {
+		val, ok := getField(record, key)
+		if !ok {
+			return false
+		}
+		ref, _ := json.Marshal(expected)
+		current, _ := json.Marshal(val)
+		if string(ref) != string(current) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyRecord(record map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	for k, v := range record {
+		res[k] = v
+	}
+	return res
+}
+
+func (m *MemoryDatabase) getTable(dbName, tbName string) ([]map[string]interface{}, error) {
+	tables, ok := m.dbs[dbName]
+	if !ok {
+		return nil, errors.New("Database " + dbName + " does not exist")
+	}
+	table, ok := tables[tbName]
+	if !ok {
+		return nil, errors.New("Table " + tbName + " does not exist")
+	}
+	return table, nil
+}
+
+//Initialize nothing to connect for the in-memory database
+func (m *MemoryDatabase) Initialize(confDb database.DatabaseConfig) error {
+	return nil
+}
+
+//CreateDB create a database
+func (m *MemoryDatabase) CreateDB(dbName string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.dbs[dbName]; ok {
+		return errors.New("Database " + dbName + " already exists")
+	}
+	m.dbs[dbName] = make(map[string][]map[string]interface{})
+	return nil
+}
+
+//CreateTable create a table
+func (m *MemoryDatabase) CreateTable(dbName, tableName string, obj interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	tables, ok := m.dbs[dbName]
+	if !ok {
+		return errors.New("Database " + dbName + " does not exist")
+	}
+	if _, ok := tables[tableName]; ok {
+		return errors.New("Table " + tableName + " already exists")
+	}
+	tables[tableName] = []map[string]interface{}{}
+	return nil
+}
+
+//DropTable remove a table and its records
+func (m *MemoryDatabase) DropTable(dbName, tableName string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, err := m.getTable(dbName, tableName)
+	if err != nil {
+		return err
+	}
+	delete(m.dbs[dbName], tableName)
+	return nil
+}
+
+//GetRecord return the first record matching the criteria or nil
+func (m *MemoryDatabase) GetRecord(dbName, tbName string, criteria map[string]interface{}) (interface{}, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	table, err := m.getTable(dbName, tbName)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range table {
+		if matchRecord(record, criteria) {
+			return copyRecord(record), nil
+		}
+	}
+	return nil, nil
+}
+
+//FetchAllRecords return all the table records
+func (m *MemoryDatabase) FetchAllRecords(dbName, tbName string) ([]interface{}, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	table, err := m.getTable(dbName, tbName)
+	if err != nil {
+		return nil, err
+	}
+	var res []interface{}
+	for _, record := range table {
+		res = append(res, copyRecord(record))
+	}
+	return res, nil
+}
+
+//InsertRecord add a record and return its id
+func (m *MemoryDatabase) InsertRecord(dbName, tbName string, obj interface{}) (string, error) {
+	record, err := toRecord(obj)
+	if err != nil {
+		return "", err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	table, err := m.getTable(dbName, tbName)
+	if err != nil {
+		return "", err
+	}
+	id, ok := record["id"].(string)
+	if !ok || id == "" {
+		m.nextID++
+		id = strconv.Itoa(m.nextID)
+		record["id"] = id
+	}
+	m.dbs[dbName][tbName] = append(table, record)
+	return id, nil
+}
+
+//UpdateRecord merge the object fields in the record with the given id
+func (m *MemoryDatabase) UpdateRecord(dbName, tbName, id string, obj interface{}) error {
+	values, err := toRecord(obj)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	table, err := m.getTable(dbName, tbName)
+	if err != nil {
+		return err
+	}
+	for _, record := range table {
+		if record["id"] != id {
+			continue
+		}
+		for k, v := range values {
+			if k == "id" {
+				continue
+			}
+			record[k] = v
+		}
+		return nil
+	}
+	return errors.New("Record " + id + " not found in " + tbName)
+}
+
+//DeleteRecord remove the records matching the object fields
+func (m *MemoryDatabase) DeleteRecord(dbName, tbName string, obj interface{}) error {
+	filter, err := toRecord(obj)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	table, err := m.getTable(dbName, tbName)
+	if err != nil {
+		return err
+	}
+	var kept []map[string]interface{}
+	for _, record := range table {
+		if !matchRecord(record, filter) {
+			kept = append(kept, record)
+		}
+	}
+	m.dbs[dbName][tbName] = kept
+	return nil
+}
+
+//DeleteRecords remove the records with the given ids
+func (m *MemoryDatabase) DeleteRecords(dbName, tbName string, ids []string) error {
+	removed := make(map[string]bool)
+	for _, id := range ids {
+		removed[id] = true
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	table, err := m.getTable(dbName, tbName)
+	if err != nil {
+		return err
+	}
+	var kept []map[string]interface{}
+	for _, record := range table {
+		id, _ := record["id"].(string)
+		if !removed[id] {
+			kept = append(kept, record)
+		}
+	}
+	m.dbs[dbName][tbName] = kept
+	return nil
+}
+
+//Close nothing to release for the in-memory database
+func (m *MemoryDatabase) Close() error {
+	return nil
+}
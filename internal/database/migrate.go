@@ -0,0 +1,140 @@
+package database
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+	"github.com/romana/rlog"
+)
+
+//dumpTable locate a table file of a RethinkDB dump: <folder>/<db>/<table>.json
+func dumpTable(name string) (string, string, bool) {
+	name = filepath.ToSlash(name)
+	if !strings.HasSuffix(name, ".json") {
+		return "", "", false
+	}
+	elts := strings.Split(name, "/")
+	if len(elts) < 2 {
+		return "", "", false
+	}
+	dbName := elts[len(elts)-2]
+	tbName := strings.TrimSuffix(elts[len(elts)-1], ".json")
+	return dbName, tbName, true
+}
+
+//importTable replace the records of a table by the dumped ones (JSON array)
+func importTable(db Database, dbName, tbName string, content io.Reader) (int, error) {
+	var records []map[string]interface{}
+	err := json.NewDecoder(content).Decode(&records)
+	if err != nil {
+		return 0, err
+	}
+	//the table may be unknown by this firmware version: keep it anyway
+	db.CreateTable(dbName, tbName, nil)
+	nb := 0
+	for _, record := range records {
+		if id, ok := record["id"]; ok {
+			criteria := make(map[string]interface{})
+			criteria["id"] = id
+			db.DeleteRecord(dbName, tbName, criteria)
+		}
+		_, err = db.InsertRecord(dbName, tbName, record)
+		if err != nil {
+			return nb, err
+		}
+		nb++
+	}
+	return nb, nil
+}
+
+//ImportRethinkDBDump import the configuration database of a RethinkDB dump, either
+//the archive created by "rethinkdb dump" or the folder created by "rethinkdb export"
+//the status database is skipped since it is rebuilt by the drivers
+func ImportRethinkDBDump(db Database, path string) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	importFile := func(name string, content io.Reader) error {
+		dbName, tbName, ok := dumpTable(name)
+		if !ok {
+			return nil
+		}
+		if dbName != pconst.DbConfig {
+			rlog.Info("Skip " + dbName + "." + tbName)
+			return nil
+		}
+		nb, err := importTable(db, dbName, tbName, content)
+		if err != nil {
+			return errors.New("Cannot import " + dbName + "." + tbName + ": " + err.Error())
+		}
+		rlog.Infof("Imported %v records in %v.%v", nb, dbName, tbName)
+		total += nb
+		return nil
+	}
+
+	if info.IsDir() {
+		err = filepath.Walk(path, func(name string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			content, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer content.Close()
+			return importFile(name, content)
+		})
+		return total, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	archive, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer archive.Close()
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		err = importFile(header.Name, reader)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+//MigrateRethinkDBDump import a RethinkDB dump in the configured embedded storage
+func MigrateRethinkDBDump(storage StorageConfig, path string) (int, error) {
+	if storage.Backend != StorageFile {
+		return 0, errors.New("The storage backend must be " + StorageFile + " to import a RethinkDB dump")
+	}
+	db, err := ConnectDatabase(storage, "", "")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return ImportRethinkDBDump(db, path)
+}
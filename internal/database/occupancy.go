@@ -0,0 +1,104 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/energieip/common-components-go/pkg/dhvac"
+)
+
+const (
+	OccupancyOccupied   = "occupied"
+	OccupancyStandby    = "standby"
+	OccupancyUnoccupied = "unoccupied"
+
+	DefaultOccupiedMode        = 0    //comfort
+	DefaultOccupancyStandby    = 300  //in s
+	DefaultOccupancyUnoccupied = 1800 //in s
+	DefaultOccupancyOverride   = 3600 //in s
+)
+
+//GroupHvacOccupancy presence driven HVAC target mode of a group
+type GroupHvacOccupancy struct {
+	Group           int  `json:"group"`
+	Enabled         bool `json:"enabled"`
+	OccupiedDelay   *int `json:"occupiedDelay,omitempty"`   //s of presence before switching to occupied
+	StandbyDelay    *int `json:"standbyDelay,omitempty"`    //s without presence before switching to standby
+	UnoccupiedDelay *int `json:"unoccupiedDelay,omitempty"` //s without presence before switching to unoccupied
+	Override        *int `json:"override,omitempty"`        //s the automation is suspended after a schedule or manual target mode, 0 until the next presence change
+	OccupiedMode    *int `json:"occupiedMode,omitempty"`    //HVAC target modes
+	StandbyMode     *int `json:"standbyMode,omitempty"`
+	UnoccupiedMode  *int `json:"unoccupiedMode,omitempty"`
+}
+
+//Check validate the HVAC occupancy settings
+func (cfg GroupHvacOccupancy) Check() error {
+	for _, value := range []*int{cfg.OccupiedDelay, cfg.StandbyDelay, cfg.UnoccupiedDelay, cfg.Override} {
+		if value != nil && *value < 0 {
+			return errors.New("Invalid negative delay")
+		}
+	}
+	for _, value := range []*int{cfg.OccupiedMode, cfg.StandbyMode, cfg.UnoccupiedMode} {
+		if value != nil && *value < 0 {
+			return errors.New("Invalid negative target mode")
+		}
+	}
+	if cfg.GetUnoccupiedDelay() < cfg.GetStandbyDelay() {
+		return errors.New("Unoccupied delay shorter than the standby delay")
+	}
+	return nil
+}
+
+func intOrDefault(value *int, def int) int {
+	if value != nil {
+		return *value
+	}
+	return def
+}
+
+//GetOccupiedDelay return the presence duration before switching to occupied in s
+func (cfg GroupHvacOccupancy) GetOccupiedDelay() int {
+	return intOrDefault(cfg.OccupiedDelay, 0)
+}
+
+//GetStandbyDelay return the absence duration before switching to standby in s
+func (cfg GroupHvacOccupancy) GetStandbyDelay() int {
+	return intOrDefault(cfg.StandbyDelay, DefaultOccupancyStandby)
+}
+
+//GetUnoccupiedDelay return the absence duration before switching to unoccupied in s
+func (cfg GroupHvacOccupancy) GetUnoccupiedDelay() int {
+	return intOrDefault(cfg.UnoccupiedDelay, DefaultOccupancyUnoccupied)
+}
+
+//GetOverride return the duration of a schedule or manual override in s
+func (cfg GroupHvacOccupancy) GetOverride() int {
+	return intOrDefault(cfg.Override, DefaultOccupancyOverride)
+}
+
+//GetMode return the HVAC target mode of an occupancy state
+func (cfg GroupHvacOccupancy) GetMode(state string) int {
+	switch state {
+	case OccupancyStandby:
+		return intOrDefault(cfg.StandbyMode, dhvac.OCCUPANCY_STANDBY)
+	case OccupancyUnoccupied:
+		return intOrDefault(cfg.UnoccupiedMode, dhvac.OCCUPANCY_ECONOMY)
+	}
+	return intOrDefault(cfg.OccupiedMode, DefaultOccupiedMode)
+}
+
+//HvacOccupancySettings HVAC occupancy settings configuration table
+var HvacOccupancySettings = GroupSettingsTable{
+	Name:  "HVAC occupancy settings",
+	Key:   "hvacOccupancy",
+	Table: HvacOccupancyTable,
+	New: func(grID int) GroupSettings {
+		return &GroupHvacOccupancy{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the HVAC occupancy settings
+func (cfg *GroupHvacOccupancy) SetGroup(grID int) {
+	cfg.Group = grID
+}
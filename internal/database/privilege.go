@@ -0,0 +1,70 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+//UserPrivilege privilege level granted locally to a user of the access table
+type UserPrivilege struct {
+	UserHash  string `json:"userHash"`
+	Privilege string `json:"privilege"`
+}
+
+//SaveUserPrivilege dump user privilege in database
+func SaveUserPrivilege(db Database, cfg UserPrivilege) error {
+	criteria := make(map[string]interface{})
+	criteria["UserHash"] = cfg.UserHash
+	return SaveOnUpdateObject(db, cfg, pconst.DbConfig, PrivilegeTable, criteria)
+}
+
+//RemoveUserPrivilege remove user privilege in database
+func RemoveUserPrivilege(db Database, userHash string) error {
+	criteria := make(map[string]interface{})
+	criteria["UserHash"] = userHash
+	return db.DeleteRecord(pconst.DbConfig, PrivilegeTable, criteria)
+}
+
+//GetUserPrivilege return the privilege of a given user or nil if none is set
+func GetUserPrivilege(db Database, userHash string) *UserPrivilege {
+	criteria := make(map[string]interface{})
+	criteria["UserHash"] = userHash
+	stored, err := db.GetRecord(pconst.DbConfig, PrivilegeTable, criteria)
+	if err != nil || stored == nil {
+		return nil
+	}
+	privilege, err := ToUserPrivilege(stored)
+	if err != nil {
+		return nil
+	}
+	return privilege
+}
+
+//GetUsersPrivilege return the privileges set locally indexed by user hash
+func GetUsersPrivilege(db Database) map[string]UserPrivilege {
+	privileges := make(map[string]UserPrivilege)
+	stored, err := db.FetchAllRecords(pconst.DbConfig, PrivilegeTable)
+	if err != nil || stored == nil {
+		return privileges
+	}
+	for _, val := range stored {
+		privilege, err := ToUserPrivilege(val)
+		if err != nil || privilege == nil {
+			continue
+		}
+		privileges[privilege.UserHash] = *privilege
+	}
+	return privileges
+}
+
+//ToUserPrivilege convert interface to UserPrivilege object
+func ToUserPrivilege(val interface{}) (*UserPrivilege, error) {
+	var privilege UserPrivilege
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &privilege)
+	return &privilege, err
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+//GroupRuntimeState group runtime state restored after a restart
+type GroupRuntimeState struct {
+	Group              int            `json:"group"`
+	Date               string         `json:"date"` //RFC3339 date of the dump
+	Auto               bool           `json:"auto"`
+	Setpoint           int            `json:"setpoint"`
+	FirstDaySetpoint   int            `json:"firstDaySetpoint"`
+	TimeToAuto         int            `json:"timeToAuto"` //in s
+	SetpointBlinds     *int           `json:"setpointBlinds,omitempty"`
+	SetpointSlatBlinds *int           `json:"setpointSlatBlinds,omitempty"`
+	ShiftTemp          *int           `json:"shiftTemp,omitempty"` //in 1/10°C
+	Presence           bool           `json:"presence"`
+	InterlockMode      *int           `json:"interlockMode,omitempty"`  //HVAC target mode forced by the window interlock
+	InterlockModes     map[string]int `json:"interlockModes,omitempty"` //HVAC target modes to restore when the window closes
+}
+
+//SaveGroupRuntimeState dump group runtime state in database
+func SaveGroupRuntimeState(db Database, state GroupRuntimeState) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = state.Group
+	return SaveOnUpdateObject(db, state, pconst.DbStatus, RuntimeTable, criteria)
+}
+
+//RemoveGroupRuntimeState remove group runtime state in database
+func RemoveGroupRuntimeState(db Database, grID int) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	return db.DeleteRecord(pconst.DbStatus, RuntimeTable, criteria)
+}
+
+//GetGroupRuntimeState return the last runtime state of a given group or nil
+func GetGroupRuntimeState(db Database, grID int) *GroupRuntimeState {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	stored, err := db.GetRecord(pconst.DbStatus, RuntimeTable, criteria)
+	if err != nil || stored == nil {
+		return nil
+	}
+	state, err := ToGroupRuntimeState(stored)
+	if err != nil {
+		return nil
+	}
+	return state
+}
+
+//ToGroupRuntimeState convert interface to GroupRuntimeState object
+func ToGroupRuntimeState(val interface{}) (*GroupRuntimeState, error) {
+	var state GroupRuntimeState
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &state)
+	return &state, err
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+//GroupScene named group preset
+type GroupScene struct {
+	Group                int    `json:"group"`
+	Scene                string `json:"scene"` //scene id (i.e presentation, cleaning, night)
+	Label                string `json:"label,omitempty"`
+	SetpointLeds         *int   `json:"setpointLeds,omitempty"`
+	SetpointLedsFirstDay *int   `json:"setpointLedsFirstDay,omitempty"`
+	SetpointBlinds       *int   `json:"setpointBlinds,omitempty"`
+	SetpointSlats        *int   `json:"setpointSlats,omitempty"`
+	SetpointTempOffset   *int   `json:"setpointTempOffset,omitempty"` //in 1/10°C
+}
+
+const (
+	MaxSlatAngle = 180 //in degrees
+)
+
+//checkBlindCommand check a blinds motor command: stop (0), up (1) or down (2)
+func checkBlindCommand(name string, value *int) error {
+	if value != nil && (*value < 0 || *value > 2) {
+		return errors.New("Invalid " + name + " " + strconv.Itoa(*value) + ", expected 0 (stop), 1 (up) or 2 (down)")
+	}
+	return nil
+}
+
+func checkSlatAngle(name string, value *int) error {
+	if value != nil && (*value < 0 || *value > MaxSlatAngle) {
+		return errors.New("Invalid " + name + " " + strconv.Itoa(*value))
+	}
+	return nil
+}
+
+func checkPercent(name string, value *int) error {
+	if value != nil && (*value < 0 || *value > 100) {
+		return errors.New("Invalid " + name + " " + strconv.Itoa(*value))
+	}
+	return nil
+}
+
+//Check validate the scene content
+func (scene GroupScene) Check() error {
+	if scene.Scene == "" {
+		return errors.New("Missing scene id")
+	}
+	if scene.SetpointLeds == nil && scene.SetpointLedsFirstDay == nil && scene.SetpointBlinds == nil && scene.SetpointSlats == nil && scene.SetpointTempOffset == nil {
+		return errors.New("Empty scene " + scene.Scene)
+	}
+	err := checkPercent("setpointLeds", scene.SetpointLeds)
+	if err != nil {
+		return err
+	}
+	err = checkPercent("setpointLedsFirstDay", scene.SetpointLedsFirstDay)
+	if err != nil {
+		return err
+	}
+	err = checkBlindCommand("setpointBlinds", scene.SetpointBlinds)
+	if err != nil {
+		return err
+	}
+	return checkSlatAngle("setpointSlats", scene.SetpointSlats)
+}
+
+//SaveGroupScene dump group scene in database
+func SaveGroupScene(db Database, cfg GroupScene) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = cfg.Group
+	criteria["Scene"] = cfg.Scene
+	return SaveOnUpdateObject(db, cfg, pconst.DbConfig, SceneTable, criteria)
+}
+
+//RemoveGroupScene remove a group scene in database
+func RemoveGroupScene(db Database, grID int, scene string) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	criteria["Scene"] = scene
+	return db.DeleteRecord(pconst.DbConfig, SceneTable, criteria)
+}
+
+//RemoveGroupScenes remove all the scenes of a group in database
+func RemoveGroupScenes(db Database, grID int) error {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	return db.DeleteRecord(pconst.DbConfig, SceneTable, criteria)
+}
+
+//GetGroupScene return a given group scene or nil if it does not exist
+func GetGroupScene(db Database, grID int, scene string) *GroupScene {
+	criteria := make(map[string]interface{})
+	criteria["Group"] = grID
+	criteria["Scene"] = scene
+	stored, err := db.GetRecord(pconst.DbConfig, SceneTable, criteria)
+	if err != nil || stored == nil {
+		return nil
+	}
+	res, err := ToGroupScene(stored)
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+//GetGroupScenes return the scenes of a group indexed by scene id
+func GetGroupScenes(db Database, grID int) map[string]GroupScene {
+	scenes := make(map[string]GroupScene)
+	stored, err := db.FetchAllRecords(pconst.DbConfig, SceneTable)
+	if err != nil || stored == nil {
+		return scenes
+	}
+	for _, val := range stored {
+		scene, err := ToGroupScene(val)
+		if err != nil || scene == nil || scene.Group != grID {
+			continue
+		}
+		scenes[scene.Scene] = *scene
+	}
+	return scenes
+}
+
+//ToGroupScene convert interface to GroupScene object
+func ToGroupScene(val interface{}) (*GroupScene, error) {
+	var scene GroupScene
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &scene)
+	return &scene, err
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/energieip/common-components-go/pkg/pconst"
+)
+
+const (
+	//ScheduleDateFormat format of the holiday dates
+	ScheduleDateFormat = "2006-01-02"
+	//ScheduleTimeFormat format of the schedule event local time
+	ScheduleTimeFormat = "15:04"
+)
+
+//ScheduleEvent group settings applied at a given local time
+type ScheduleEvent struct {
+	Days            []int  `json:"days,omitempty"` //week days from 0 (sunday) to 6 (saturday), every day when empty
+	Time            string `json:"time"`           //local time HH:MM
+	Auto            *bool  `json:"auto,omitempty"`
+	SetpointLeds    *int   `json:"setpointLeds,omitempty"`
+	SetpointBlinds  *int   `json:"setpointBlinds,omitempty"`
+	SetpointSlats   *int   `json:"setpointSlats,omitempty"`
+	HvacsTargetMode *int   `json:"hvacsTargetMode,omitempty"` //occupied/standby/economy
+	Calibration     *bool  `json:"calibration,omitempty"`     //start the sensors calibration
+}
+
+//GroupSchedule weekly and holiday schedules of a group
+type GroupSchedule struct {
+	Group         int             `json:"group"`
+	Weekly        []ScheduleEvent `json:"weekly"`
+	Holidays      []string        `json:"holidays"`      //dates YYYY-MM-DD
+	HolidayEvents []ScheduleEvent `json:"holidayEvents"` //replace the weekly events during the holidays
+}
+
+//Check validate the schedule event content
+func (evt ScheduleEvent) Check() error {
+	_, err := time.Parse(ScheduleTimeFormat, evt.Time)
+	if err != nil {
+		return errors.New("Invalid time " + evt.Time)
+	}
+	for _, day := range evt.Days {
+		if day < 0 || day > 6 {
+			return errors.New("Invalid week day " + strconv.Itoa(day))
+		}
+	}
+	if evt.Auto == nil && evt.SetpointLeds == nil && evt.SetpointBlinds == nil && evt.SetpointSlats == nil && evt.HvacsTargetMode == nil && evt.Calibration == nil {
+		return errors.New("Empty schedule event at " + evt.Time)
+	}
+	return nil
+}
+
+//Check validate the group schedule content
+func (sched GroupSchedule) Check() error {
+	for _, day := range sched.Holidays {
+		_, err := time.Parse(ScheduleDateFormat, day)
+		if err != nil {
+			return errors.New("Invalid holiday date " + day)
+		}
+	}
+	for _, evt := range sched.Weekly {
+		err := evt.Check()
+		if err != nil {
+			return err
+		}
+	}
+	for _, evt := range sched.HolidayEvents {
+		err := evt.Check()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//IsHoliday check if the given date is a holiday for the group
+func (sched GroupSchedule) IsHoliday(date time.Time) bool {
+	day := date.Format(ScheduleDateFormat)
+	for _, holiday := range sched.Holidays {
+		if holiday == day {
+			return true
+		}
+	}
+	return false
+}
+
+//EventsAt return the events planned at the given local time (minute precision)
+func (sched GroupSchedule) EventsAt(date time.Time) []ScheduleEvent {
+	var res []ScheduleEvent
+	events := sched.Weekly
+	if sched.IsHoliday(date) {
+		events = sched.HolidayEvents
+	}
+	current := date.Format(ScheduleTimeFormat)
+	for _, evt := range events {
+		if evt.Time != current {
+			continue
+		}
+		if len(evt.Days) == 0 {
+			res = append(res, evt)
+			continue
+		}
+		for _, day := range evt.Days {
+			if day == int(date.Weekday()) {
+				res = append(res, evt)
+				break
+			}
+		}
+	}
+	return res
+}
+
+//ScheduleSettings schedule configuration table
+var ScheduleSettings = GroupSettingsTable{
+	Name:  "schedule",
+	Key:   "schedules",
+	Table: ScheduleTable,
+	New: func(grID int) GroupSettings {
+		return &GroupSchedule{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the schedule
+func (cfg *GroupSchedule) SetGroup(grID int) {
+	cfg.Group = grID
+}
+
+//GetGroupsSchedule return the group schedules indexed by group
+func GetGroupsSchedule(db Database) map[int]GroupSchedule {
+	schedules := make(map[int]GroupSchedule)
+	stored, err := db.FetchAllRecords(pconst.DbConfig, ScheduleTable)
+	if err != nil || stored == nil {
+		return schedules
+	}
+	for _, val := range stored {
+		sched, err := ToGroupSchedule(val)
+		if err != nil || sched == nil {
+			continue
+		}
+		schedules[sched.Group] = *sched
+	}
+	return schedules
+}
+
+//ToGroupSchedule convert interface to GroupSchedule object
+func ToGroupSchedule(val interface{}) (*GroupSchedule, error) {
+	var sched GroupSchedule
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &sched)
+	return &sched, err
+}
@@ -1,10 +1,39 @@
 package database
 
 import (
+	"encoding/json"
+	"errors"
+
 	ds "github.com/energieip/common-components-go/pkg/dsensor"
 	"github.com/energieip/common-components-go/pkg/pconst"
 )
 
+const (
+	DefaultSensorWeight = 1.0
+)
+
+//SensorSetup sensor setup with the weight of the sensor in the group weighted rule
+type SensorSetup struct {
+	ds.SensorSetup
+	Weight *float64 `json:"weight,omitempty"` //1 when not set, 0 to ignore the sensor
+}
+
+//Check validate the sensor weight
+func (setup SensorSetup) Check() error {
+	if setup.Weight != nil && *setup.Weight < 0 {
+		return errors.New("Invalid negative weight for " + setup.Mac)
+	}
+	return nil
+}
+
+//GetWeight return the weight of the sensor in the group weighted rule
+func (setup SensorSetup) GetWeight() float64 {
+	if setup.Weight == nil {
+		return DefaultSensorWeight
+	}
+	return *setup.Weight
+}
+
 func GetSensorConfig(db Database, mac string) (*ds.SensorSetup, string) {
 	var dbID string
 	criteria := make(map[string]interface{})
@@ -47,8 +76,34 @@ func SaveSensorConfig(db Database, cfg ds.SensorConf) error {
 }
 
 //SaveSensorSetup dump sensor config in database
-func SaveSensorSetup(db Database, cfg ds.SensorSetup) error {
+func SaveSensorSetup(db Database, cfg SensorSetup) error {
 	criteria := make(map[string]interface{})
 	criteria["Mac"] = cfg.Mac
 	return SaveOnUpdateObject(db, cfg, pconst.DbConfig, pconst.TbSensors, criteria)
 }
+
+//GetSensorWeight return the weight of a sensor from its setup, 1 when not set
+func GetSensorWeight(db Database, mac string) float64 {
+	criteria := make(map[string]interface{})
+	criteria["Mac"] = mac
+	stored, err := db.GetRecord(pconst.DbConfig, pconst.TbSensors, criteria)
+	if err != nil || stored == nil {
+		return DefaultSensorWeight
+	}
+	setup, err := ToSensorSetup(stored)
+	if err != nil {
+		return DefaultSensorWeight
+	}
+	return setup.GetWeight()
+}
+
+//ToSensorSetup convert interface to SensorSetup object
+func ToSensorSetup(val interface{}) (*SensorSetup, error) {
+	var setup SensorSetup
+	inrec, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(inrec, &setup)
+	return &setup, err
+}
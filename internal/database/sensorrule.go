@@ -0,0 +1,89 @@
+package database
+
+import (
+	"errors"
+
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+)
+
+const (
+	SensorWeighted = "weighted" //average weighted by the sensors setup weights
+	SensorMedian   = "median"
+	SensorOutlier  = "outlier" //average of the readings close to the median
+
+	SensorMetricBrightness  = "brightness"
+	SensorMetricTemperature = "temperature" //temperature, hygrometry and HVACs setpoints
+	SensorMetricAirQuality  = "airQuality"  //CO2 and COV
+
+	DefaultSensorOutlierDeviation = 20 //in % of the median
+)
+
+//IsSensorRule check that a group sensor rule is supported
+func IsSensorRule(rule string) bool {
+	switch rule {
+	case gm.SensorAverage, gm.SensorMax, gm.SensorMin, SensorWeighted, SensorMedian, SensorOutlier:
+		return true
+	}
+	return false
+}
+
+//GroupSensorRules sensor aggregation rules of a group by metric
+type GroupSensorRules struct {
+	Group            int     `json:"group"`
+	Brightness       *string `json:"brightness,omitempty"`       //group sensor rule when not set
+	Temperature      *string `json:"temperature,omitempty"`      //group sensor rule when not set
+	AirQuality       *string `json:"airQuality,omitempty"`       //group sensor rule when not set
+	OutlierDeviation *int    `json:"outlierDeviation,omitempty"` //% of the median, farther readings are dropped
+}
+
+//Check validate the sensor rules
+func (cfg GroupSensorRules) Check() error {
+	for _, rule := range []*string{cfg.Brightness, cfg.Temperature, cfg.AirQuality} {
+		if rule != nil && !IsSensorRule(*rule) {
+			return errors.New("Invalid sensor rule " + *rule)
+		}
+	}
+	if cfg.OutlierDeviation != nil && *cfg.OutlierDeviation <= 0 {
+		return errors.New("Invalid outlier deviation")
+	}
+	return nil
+}
+
+//GetRule return the rule of a metric, def when not set
+func (cfg GroupSensorRules) GetRule(metric string, def string) string {
+	var rule *string
+	switch metric {
+	case SensorMetricBrightness:
+		rule = cfg.Brightness
+	case SensorMetricTemperature:
+		rule = cfg.Temperature
+	case SensorMetricAirQuality:
+		rule = cfg.AirQuality
+	}
+	if rule != nil {
+		return *rule
+	}
+	return def
+}
+
+//GetOutlierDeviation return the maximum deviation from the median in %
+func (cfg GroupSensorRules) GetOutlierDeviation() int {
+	return intOrDefault(cfg.OutlierDeviation, DefaultSensorOutlierDeviation)
+}
+
+//SensorRuleSettings sensor rules configuration table
+var SensorRuleSettings = GroupSettingsTable{
+	Name:  "sensor rules",
+	Key:   "sensorRules",
+	Table: SensorRulesTable,
+	New: func(grID int) GroupSettings {
+		return &GroupSensorRules{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the sensor rules
+func (cfg *GroupSensorRules) SetGroup(grID int) {
+	cfg.Group = grID
+}
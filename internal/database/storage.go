@@ -0,0 +1,78 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	"github.com/energieip/common-components-go/pkg/database"
+	"github.com/romana/rlog"
+)
+
+const (
+	StorageRethinkDB = database.RETHINKDB
+	StorageFile      = "file"   //embedded JSON file, configuration only is persisted
+	StorageMemory    = "memory" //nothing persisted
+
+	DefaultStoragePath = "/var/lib/energieip-swh200-firmware/database.json"
+
+	DefaultRuntimeMaxAge = 900 //s
+)
+
+//StorageConfig storage backend selection
+type StorageConfig struct {
+	Backend       string `json:"backend"`
+	Path          string `json:"path"`
+	RuntimeMaxAge *int   `json:"runtimeMaxAge"` //s, maximum age of the group runtime states restored on start, 0 to disable
+}
+
+type storageServiceConfig struct {
+	Storage *StorageConfig `json:"storage"`
+}
+
+//ReadStorageConfig read the optional storage section of the service configuration file
+//RethinkDB is used when not set
+func ReadStorageConfig(confFile string) (StorageConfig, error) {
+	maxAge := DefaultRuntimeMaxAge
+	storage := StorageConfig{
+		Backend:       StorageRethinkDB,
+		RuntimeMaxAge: &maxAge,
+	}
+	content, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		return storage, err
+	}
+	var cfg storageServiceConfig
+	err = json.Unmarshal(content, &cfg)
+	if err != nil {
+		return storage, err
+	}
+	if cfg.Storage == nil {
+		return storage, nil
+	}
+	if cfg.Storage.Backend != "" {
+		storage.Backend = cfg.Storage.Backend
+	}
+	storage.Path = cfg.Storage.Path
+	if cfg.Storage.RuntimeMaxAge != nil {
+		storage.RuntimeMaxAge = cfg.Storage.RuntimeMaxAge
+	}
+	if storage.Backend == StorageFile && storage.Path == "" {
+		storage.Path = DefaultStoragePath
+	}
+	return storage, nil
+}
+
+func newStorage(storage StorageConfig) (Database, error) {
+	switch storage.Backend {
+	case StorageRethinkDB, "":
+		return database.NewDatabase(database.RETHINKDB)
+	case StorageFile:
+		rlog.Info("Use file storage " + storage.Path)
+		return NewFileDatabase(storage.Path), nil
+	case StorageMemory:
+		rlog.Warn("Use memory storage: the configuration will be lost on restart")
+		return NewMemoryDatabase(), nil
+	}
+	return nil, errors.New("Unknown storage backend " + storage.Backend)
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"errors"
+)
+
+const (
+	DefaultThermalKp          = 20.0 //in %/°C
+	DefaultThermalKi          = 0.02 //in %/(°C.s)
+	DefaultThermalDeadBand    = 5    //in 1/10°C
+	DefaultThermalValveClosed = 50   //in %
+)
+
+//GroupThermalControl switch side temperature controller of a group driving the HVACs valves and dampers
+type GroupThermalControl struct {
+	Group       int      `json:"group"`
+	Enabled     bool     `json:"enabled"`
+	Kp          *float64 `json:"kp,omitempty"`          //proportional gain in %/°C
+	Ki          *float64 `json:"ki,omitempty"`          //integral gain in %/(°C.s)
+	DeadBand    *int     `json:"deadBand,omitempty"`    //no correction when the temperature error is within the dead band (1/10°C)
+	ValveClosed *int     `json:"valveClosed,omitempty"` //6 ways valve position without demand in %, 0 is full cooling and 100 full heating
+	DamperMin   *int     `json:"damperMin,omitempty"`   //damper position without demand in %
+}
+
+//Check validate the thermal control settings
+func (cfg GroupThermalControl) Check() error {
+	if cfg.Kp != nil && *cfg.Kp < 0 {
+		return errors.New("Invalid negative kp")
+	}
+	if cfg.Ki != nil && *cfg.Ki < 0 {
+		return errors.New("Invalid negative ki")
+	}
+	if cfg.DeadBand != nil && *cfg.DeadBand < 0 {
+		return errors.New("Invalid negative dead band")
+	}
+	if cfg.ValveClosed != nil && (*cfg.ValveClosed < 0 || *cfg.ValveClosed > 100) {
+		return errors.New("Invalid valve closed position")
+	}
+	if cfg.DamperMin != nil && (*cfg.DamperMin < 0 || *cfg.DamperMin > 100) {
+		return errors.New("Invalid damper minimum position")
+	}
+	return nil
+}
+
+//GetKp return the proportional gain
+func (cfg GroupThermalControl) GetKp() float64 {
+	if cfg.Kp != nil {
+		return *cfg.Kp
+	}
+	return DefaultThermalKp
+}
+
+//GetKi return the integral gain
+func (cfg GroupThermalControl) GetKi() float64 {
+	if cfg.Ki != nil {
+		return *cfg.Ki
+	}
+	return DefaultThermalKi
+}
+
+//GetDeadBand return the dead band in 1/10°C
+func (cfg GroupThermalControl) GetDeadBand() int {
+	return intOrDefault(cfg.DeadBand, DefaultThermalDeadBand)
+}
+
+//GetValveClosed return the 6 ways valve position without demand
+func (cfg GroupThermalControl) GetValveClosed() int {
+	return intOrDefault(cfg.ValveClosed, DefaultThermalValveClosed)
+}
+
+//GetDamperMin return the damper position without demand
+func (cfg GroupThermalControl) GetDamperMin() int {
+	return intOrDefault(cfg.DamperMin, 0)
+}
+
+//ThermalControlSettings thermal control settings configuration table
+var ThermalControlSettings = GroupSettingsTable{
+	Name:  "thermal control settings",
+	Key:   "thermalControl",
+	Table: ThermalControlTable,
+	New: func(grID int) GroupSettings {
+		return &GroupThermalControl{
+			Group: grID,
+		}
+	},
+}
+
+//SetGroup set the group of the thermal control settings
+func (cfg *GroupThermalControl) SetGroup(grID int) {
+	cfg.Group = grID
+}
@@ -16,6 +16,8 @@ func SaveUserConfig(db Database, cfg duser.UserAccess) error {
 func RemoveUserConfig(db Database, userHash string) error {
 	criteria := make(map[string]interface{})
 	criteria["UserHash"] = userHash
+	RemoveUserPrivilege(db, userHash)
+	RemoveUserCredentials(db, userHash)
 	return db.DeleteRecord(pconst.DbConfig, AccessTable, criteria)
 }
 
@@ -34,6 +36,23 @@ func GetUser(db Database, userHash string) *duser.UserAccess {
 	return user
 }
 
+//GetUsers return all the users allowed on the switch indexed by user hash
+func GetUsers(db Database) map[string]duser.UserAccess {
+	users := make(map[string]duser.UserAccess)
+	stored, err := db.FetchAllRecords(pconst.DbConfig, AccessTable)
+	if err != nil || stored == nil {
+		return users
+	}
+	for _, val := range stored {
+		usr, err := duser.ToUserAccess(val)
+		if err != nil || usr == nil {
+			continue
+		}
+		users[usr.UserHash] = *usr
+	}
+	return users
+}
+
 //GetUserConfigs get user Config for a given group list
 func GetUserConfigs(db Database, groups map[int]bool) map[string]duser.UserAccess {
 	users := make(map[string]duser.UserAccess)
@@ -0,0 +1,187 @@
+package fake
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	genericNetwork "github.com/energieip/common-components-go/pkg/network"
+	"github.com/energieip/swh200-firmware-go/internal/core"
+)
+
+//Command message sent by the service
+type Command struct {
+	Topic   string
+	Content string
+}
+
+//Message received message
+type Message struct {
+	topic   string
+	payload []byte
+}
+
+//Broker in-memory MQTT broker connection
+type Broker struct {
+	mutex     sync.Mutex
+	config    *genericNetwork.NetworkConfig
+	connected bool
+	sent      []Command
+}
+
+//Brokers create and keep the broker connections requested by the service
+type Brokers struct {
+	mutex   sync.Mutex
+	brokers []*Broker
+}
+
+//NewBroker create a disconnected broker
+func NewBroker() *Broker {
+	return &Broker{}
+}
+
+//Initialize connect the broker and register the callbacks
+func (b *Broker) Initialize(config genericNetwork.NetworkConfig) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.config = &config
+	b.connected = true
+	return nil
+}
+
+//Disconnect close the connection
+func (b *Broker) Disconnect() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.connected = false
+}
+
+//SendCommand record a message sent by the service
+func (b *Broker) SendCommand(topic, content string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.connected {
+		return errors.New("Not connected")
+	}
+	b.sent = append(b.sent, Command{Topic: topic, Content: content})
+	return nil
+}
+
+//Config return the connection configuration or nil when not initialized
+func (b *Broker) Config() *genericNetwork.NetworkConfig {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.config
+}
+
+//Connected return the connection state
+func (b *Broker) Connected() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.connected
+}
+
+//Sent return the messages sent on the topics matching the filter
+func (b *Broker) Sent(filter string) []Command {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var res []Command
+	for _, cmd := range b.sent {
+		if MatchTopic(filter, cmd.Topic) {
+			res = append(res, cmd)
+		}
+	}
+	return res
+}
+
+//Publish deliver a message to the service callbacks subscribed on the topic
+func (b *Broker) Publish(topic string, payload string) int {
+	b.mutex.Lock()
+	var callbacks []func(genericNetwork.Client, genericNetwork.Message)
+	if b.config != nil && b.connected {
+		for filter, cbk := range b.config.Callbacks {
+			if MatchTopic(filter, topic) {
+				callbacks = append(callbacks, cbk)
+			}
+		}
+	}
+	b.mutex.Unlock()
+	for _, cbk := range callbacks {
+		cbk(nil, &Message{topic: topic, payload: []byte(payload)})
+	}
+	return len(callbacks)
+}
+
+//New create a broker connection, to be used as core.Platform.NewBroker
+func (f *Brokers) New() (core.Broker, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	broker := NewBroker()
+	f.brokers = append(f.brokers, broker)
+	return broker, nil
+}
+
+//Get return the broker connected to the given IP and port or nil
+func (f *Brokers) Get(ip, port string) *Broker {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, broker := range f.brokers {
+		cfg := broker.Config()
+		if cfg != nil && cfg.IP == ip && cfg.Port == port {
+			return broker
+		}
+	}
+	return nil
+}
+
+//MatchTopic check a topic against a MQTT subscription filter (+ and # wildcards)
+func MatchTopic(filter, topic string) bool {
+	filters := strings.Split(filter, "/")
+	topics := strings.Split(topic, "/")
+	for i, elt := range filters {
+		if elt == "#" {
+			return true
+		}
+		if i >= len(topics) {
+			return false
+		}
+		if elt != "+" && elt != topics[i] {
+			return false
+		}
+	}
+	return len(filters) == len(topics)
+}
+
+//Duplicate always false
+func (m *Message) Duplicate() bool {
+	return false
+}
+
+//Qos always 0
+func (m *Message) Qos() byte {
+	return 0
+}
+
+//Retained always false
+func (m *Message) Retained() bool {
+	return false
+}
+
+//Topic message topic
+func (m *Message) Topic() string {
+	return m.topic
+}
+
+//MessageID always 0
+func (m *Message) MessageID() uint16 {
+	return 0
+}
+
+//Payload message content
+func (m *Message) Payload() []byte {
+	return m.payload
+}
+
+//Ack nothing to acknowledge
+func (m *Message) Ack() {
+}
@@ -0,0 +1,162 @@
+package fake
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/energieip/swh200-firmware-go/internal/core"
+)
+
+//Clock manual time source: the time only moves with Advance
+type Clock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	deadline time.Time
+	period   time.Duration //0 for a sleep or a timer
+	ch       chan time.Time
+	fn       func() //timer callback
+}
+
+//Ticker ticker driven by the fake clock
+type Ticker struct {
+	clock  *Clock
+	waiter *waiter
+}
+
+//Timer callback timer driven by the fake clock
+type Timer struct {
+	clock  *Clock
+	waiter *waiter
+}
+
+//NewClock create a clock starting at the given time
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now: now,
+	}
+}
+
+//Now return the current fake time
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+//Sleep block until the clock is advanced by d
+func (c *Clock) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	w := &waiter{
+		deadline: c.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+	c.waiters = append(c.waiters, w)
+	c.mutex.Unlock()
+	<-w.ch
+}
+
+//NewTicker create a ticker firing when the clock is advanced
+func (c *Clock) NewTicker(d time.Duration) core.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := &waiter{
+		deadline: c.now.Add(d),
+		period:   d,
+		ch:       make(chan time.Time, 1),
+	}
+	c.waiters = append(c.waiters, w)
+	return &Ticker{clock: c, waiter: w}
+}
+
+//AfterFunc call f when the clock is advanced by d
+func (c *Clock) AfterFunc(d time.Duration, f func()) core.Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := &waiter{
+		deadline: c.now.Add(d),
+		fn:       f,
+	}
+	c.waiters = append(c.waiters, w)
+	return &Timer{clock: c, waiter: w}
+}
+
+//Advance move the time forward and fire the expired tickers, sleeps and timers in order,
+//the timers callbacks are called by Advance
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	target := c.now.Add(d)
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool {
+			return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(target) {
+			break
+		}
+		w := c.waiters[0]
+		c.now = w.deadline
+		if w.fn != nil {
+			c.waiters = c.waiters[1:]
+			c.mutex.Unlock()
+			w.fn()
+			c.mutex.Lock()
+			continue
+		}
+		select {
+		case w.ch <- c.now:
+		default:
+			//ticker not read: drop the tick like time.Ticker
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = target
+}
+
+//Waiters return the number of pending tickers and sleeps
+func (c *Clock) Waiters() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.waiters)
+}
+
+func (c *Clock) remove(w *waiter) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, elt := range c.waiters {
+		if elt == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//C return the ticker channel
+func (t *Ticker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+//Stop stop the ticker
+func (t *Ticker) Stop() {
+	t.clock.remove(t.waiter)
+}
+
+//Stop cancel the timer, return false when it already fired or was stopped
+func (t *Timer) Stop() bool {
+	return t.clock.remove(t.waiter)
+}
@@ -0,0 +1,15 @@
+//Package fake in-memory hardware, system, clock, brokers and API server to run the core service
+//on a plain Linux machine:
+//
+//	brokers := &fake.Brokers{}
+//	s := core.NewService(core.Platform{
+//		Hardware:  fake.NewHardware(),
+//		System:    fake.NewSystem("00:11:22:33:44:55", "10.0.0.2"),
+//		Clock:     fake.NewClock(time.Now()),
+//		NewBroker: brokers.New,
+//		Database:  database.NewMemoryDatabase(),
+//		APIServer: fake.NewAPIServer(),
+//		Config:    &conf,
+//	})
+//	s.Initialize(confFile)
+package fake
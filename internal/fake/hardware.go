@@ -0,0 +1,55 @@
+package fake
+
+import (
+	"sync"
+)
+
+//Hardware in-memory GPIOs
+type Hardware struct {
+	mutex  sync.Mutex
+	gpios  map[int]int
+	writes []GPIOWrite
+}
+
+//GPIOWrite GPIO write request
+type GPIOWrite struct {
+	GPIO  int
+	Value int
+}
+
+//NewHardware create GPIOs all set to 0
+func NewHardware() *Hardware {
+	return &Hardware{
+		gpios: make(map[int]int),
+	}
+}
+
+//WriteGPIO set a GPIO value
+func (hw *Hardware) WriteGPIO(gpio int, value int) error {
+	hw.mutex.Lock()
+	defer hw.mutex.Unlock()
+	hw.gpios[gpio] = value
+	hw.writes = append(hw.writes, GPIOWrite{GPIO: gpio, Value: value})
+	return nil
+}
+
+//ReadGPIO return a GPIO value
+func (hw *Hardware) ReadGPIO(gpio int) (int, error) {
+	hw.mutex.Lock()
+	defer hw.mutex.Unlock()
+	return hw.gpios[gpio], nil
+}
+
+//SetGPIO simulate an input level (push buttons, BAES)
+func (hw *Hardware) SetGPIO(gpio int, value int) {
+	hw.mutex.Lock()
+	defer hw.mutex.Unlock()
+	hw.gpios[gpio] = value
+}
+
+//Writes return the GPIO writes in order
+func (hw *Hardware) Writes() []GPIOWrite {
+	hw.mutex.Lock()
+	defer hw.mutex.Unlock()
+	return append([]GPIOWrite{}, hw.writes...)
+}
@@ -0,0 +1,47 @@
+package fake
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+//APIServer keep the API handler instead of listening on the network
+type APIServer struct {
+	mutex   sync.Mutex
+	handler http.Handler
+	ready   chan bool
+}
+
+//NewAPIServer create a server waiting for the API handler
+func NewAPIServer() *APIServer {
+	return &APIServer{
+		ready: make(chan bool),
+	}
+}
+
+//ListenAndServe record the handler and return immediately
+func (srv *APIServer) ListenAndServe(handler http.Handler) error {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if srv.handler == nil {
+		srv.handler = handler
+		close(srv.ready)
+	}
+	return nil
+}
+
+//Handler return the API handler, waiting for the service to start it
+func (srv *APIServer) Handler() http.Handler {
+	<-srv.ready
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	return srv.handler
+}
+
+//Do serve a request and return the recorded response
+func (srv *APIServer) Do(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,480 @@
+package fake
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dl "github.com/energieip/common-components-go/pkg/dblind"
+	gm "github.com/energieip/common-components-go/pkg/dgroup"
+	dled "github.com/energieip/common-components-go/pkg/dled"
+	"github.com/energieip/common-components-go/pkg/duser"
+	pkg "github.com/energieip/common-components-go/pkg/service"
+	"github.com/energieip/swh200-firmware-go/internal/core"
+	"github.com/energieip/swh200-firmware-go/internal/database"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	switchMac   = "00:11:22:33:44:55"
+	ledMac      = "AA:00:00:00:00:01"
+	blindMac    = "BB:00:00:00:00:01"
+	sensorMac   = "CC:00:00:00:00:01"
+	apiPassword = "secret"
+)
+
+// testSwitch service running on the fake platform
+type testSwitch struct {
+	service  *core.Service
+	clock    *Clock
+	hardware *Hardware
+	system   *System
+	brokers  *Brokers
+	server   *APIServer
+	db       database.Database
+	conf     pkg.ServiceConfig
+}
+
+func newTestSwitch(t *testing.T, setup func(db database.Database)) *testSwitch {
+	dir, err := ioutil.TempDir("", "swh200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	confFile := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(confFile, []byte(`{"storage": {"backend": "memory"}, "offline": {"path": ""}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sw := &testSwitch{
+		clock:    NewClock(time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC)),
+		hardware: NewHardware(),
+		system:   NewSystem(strings.ToLower(switchMac), "10.0.0.2"),
+		brokers:  &Brokers{},
+		server:   NewAPIServer(),
+		db:       database.NewMemoryDatabase(),
+		conf: pkg.ServiceConfig{
+			LocalBroker:   pkg.Broker{IP: "127.0.0.1", Port: "1883"},
+			NetworkBroker: pkg.Broker{IP: "10.0.0.1", Port: "8883"},
+			ExternalAPI:   pkg.APIInfo{IP: "10.0.0.2", Port: "8888", Password: apiPassword},
+			LogLevel:      "ERROR",
+		},
+	}
+	if setup != nil {
+		database.PrepareDB(sw.db)
+		setup(sw.db)
+	}
+	sw.service = core.NewService(core.Platform{
+		Hardware:  sw.hardware,
+		System:    sw.system,
+		Clock:     sw.clock,
+		NewBroker: sw.brokers.New,
+		Database:  sw.db,
+		APIServer: sw.server,
+		Config:    &sw.conf,
+	})
+	err = sw.service.Initialize(confFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "server broker connection", func() bool {
+		server := sw.serverBroker()
+		return server != nil && server.Connected()
+	})
+	return sw
+}
+
+func (sw *testSwitch) localBroker() *Broker {
+	return sw.brokers.Get(sw.conf.LocalBroker.IP, sw.conf.LocalBroker.Port)
+}
+
+func (sw *testSwitch) serverBroker() *Broker {
+	return sw.brokers.Get(sw.conf.NetworkBroker.IP, sw.conf.NetworkBroker.Port)
+}
+
+func (sw *testSwitch) get(url string, auth bool) (int, string) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if auth {
+		req.SetBasicAuth("admin", apiPassword)
+	}
+	rec := sw.server.Do(req)
+	return rec.Code, rec.Body.String()
+}
+
+func (sw *testSwitch) send(method string, url string, body string) (int, string) {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.SetBasicAuth("admin", apiPassword)
+	rec := sw.server.Do(req)
+	return rec.Code, rec.Body.String()
+}
+
+// eventually wait for the service goroutines to reach the expected state
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for " + what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// advanceUntil move the clock by steps until the condition is fulfilled
+func advanceUntil(t *testing.T, clock *Clock, step time.Duration, max time.Duration, what string, cond func() bool) {
+	t.Helper()
+	for elapsed := time.Duration(0); elapsed <= max; elapsed += step {
+		deadline := time.Now().Add(20 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(step)
+	}
+	t.Fatal("timeout waiting for " + what)
+}
+
+func TestServiceStartup(t *testing.T) {
+	sw := newTestSwitch(t, nil)
+
+	local := sw.localBroker()
+	if local == nil || !local.Connected() {
+		t.Fatal("drivers broker not connected")
+	}
+	if local.Publish("/read/led/"+ledMac+"/setup/hello", "{}") == 0 {
+		t.Error("no subscription for the LEDs hello")
+	}
+
+	code, _ := sw.get("/v1.0/status/leds", false)
+	if code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated request status %v", code)
+	}
+	code, body := sw.get("/versions", false)
+	if code != http.StatusOK || !strings.Contains(body, "v1.0") {
+		t.Errorf("versions %v %v", code, body)
+	}
+}
+
+func TestServiceBoot(t *testing.T) {
+	sw := newTestSwitch(t, nil)
+
+	//the BAES is switched off once the expected LEDs (none) are seen
+	advanceUntil(t, sw.clock, time.Second, 10*time.Second, "BAES off", func() bool {
+		value, _ := sw.hardware.ReadGPIO(43)
+		return value == 1
+	})
+	//PSE activation sequence
+	advanceUntil(t, sw.clock, time.Second, time.Minute, "PSE 2 activation", func() bool {
+		value, _ := sw.hardware.ReadGPIO(1)
+		return value == 1
+	})
+	//no driver plugged after 5 minutes: reboot
+	advanceUntil(t, sw.clock, 10*time.Second, 10*time.Minute, "reboot", func() bool {
+		return sw.system.Reboots() > 0
+	})
+}
+
+func TestServiceRunHello(t *testing.T) {
+	sw := newTestSwitch(t, nil)
+	go sw.service.Run()
+
+	server := sw.serverBroker()
+	advanceUntil(t, sw.clock, time.Second, time.Minute, "switch hello", func() bool {
+		return len(server.Sent("/read/switch/"+switchMac+"/setup/hello")) > 0
+	})
+	var hello map[string]interface{}
+	json.Unmarshal([]byte(server.Sent("/read/switch/" + switchMac + "/setup/hello")[0].Content), &hello)
+	if hello["ip"] != "10.0.0.2" {
+		t.Errorf("hello %v", hello)
+	}
+}
+
+func TestServiceDriverJournal(t *testing.T) {
+	sw := newTestSwitch(t, nil)
+
+	sw.localBroker().Publish("/read/led/"+strings.ToLower(ledMac)+"/setup/hello", `{"mac": "`+strings.ToLower(ledMac)+`"}`)
+	if _, ok := sw.service.GetLeds()[ledMac]; !ok {
+		t.Fatalf("LED not registered: %v", sw.service.GetLeds())
+	}
+
+	journal := sw.serverBroker().Sent("/read/switch/" + switchMac + "/events/journal")
+	if len(journal) != 1 {
+		t.Fatalf("journal messages %v", journal)
+	}
+	var entry database.JournalEntry
+	json.Unmarshal([]byte(journal[0].Content), &entry)
+	if entry.Mac != ledMac || entry.Event != database.JournalHello || entry.Date != "2020-06-01T08:00:00Z" {
+		t.Errorf("journal entry %+v", entry)
+	}
+
+	code, body := sw.get("/v1.0/journal?mac="+ledMac, true)
+	if code != http.StatusOK || !strings.Contains(body, database.JournalHello) {
+		t.Errorf("journal API %v %v", code, body)
+	}
+	code, body = sw.get("/v1.0/status/leds", true)
+	if code != http.StatusOK || !strings.Contains(body, ledMac) {
+		t.Errorf("LEDs API %v %v", code, body)
+	}
+}
+
+func TestServiceButtonLongPress(t *testing.T) {
+	longPress := 2000
+	sw := newTestSwitch(t, func(db database.Database) {
+		database.UpdateGroupConfig(db, gm.GroupConfig{
+			Group:  1,
+			Blinds: []string{blindMac},
+		})
+		database.SaveGroupSettings(db, database.ButtonSettings, 1, &database.GroupButtons{
+			LongPress: &longPress,
+			Actions: []database.ButtonAction{
+				{Button: "A1", Press: database.ButtonPressLong, Action: database.ButtonActionBlindsDown},
+			},
+		})
+	})
+	local := sw.localBroker()
+	local.Publish("/read/blind/"+blindMac+"/setup/hello", `{"mac": "`+blindMac+`"}`)
+	blindTopic := "/write/blind/" + blindMac + "/update/settings"
+	sent := len(local.Sent(blindTopic))
+
+	local.Publish("/write/group/1/commands", `{"group": 1, "action": true, "button_A": true}`)
+	sw.clock.Advance(time.Second)
+	time.Sleep(20 * time.Millisecond)
+	if len(local.Sent(blindTopic)) != sent {
+		t.Fatal("long press action before the long press duration")
+	}
+	sw.clock.Advance(time.Second)
+	eventually(t, "blinds down", func() bool {
+		return len(local.Sent(blindTopic)) > sent
+	})
+	last := local.Sent(blindTopic)[len(local.Sent(blindTopic))-1]
+	var conf dl.BlindConf
+	json.Unmarshal([]byte(last.Content), &conf)
+	if conf.Blind1 == nil || *conf.Blind1 != core.BlindDown || conf.Blind2 == nil || *conf.Blind2 != core.BlindDown {
+		t.Errorf("blind command %v %v", last.Topic, last.Content)
+	}
+}
+
+func TestServiceGroupSettings(t *testing.T) {
+	sw := newTestSwitch(t, func(db database.Database) {
+		database.UpdateGroupConfig(db, gm.GroupConfig{
+			Group:  1,
+			Blinds: []string{blindMac},
+		})
+	})
+
+	code, body := sw.send("POST", "/v1.0/groups/1/airQuality", `{"group": 2, "enabled": true, "co2Threshold": 800, "windows": {"`+strings.ToLower(blindMac)+`": 2}}`)
+	if code != http.StatusOK {
+		t.Fatalf("air quality update %v %v", code, body)
+	}
+	cfg, _ := database.GetGroupSettings(sw.db, database.AirQualitySettings, 1).(*database.GroupAirQuality)
+	if cfg == nil || cfg.Group != 1 || cfg.GetCO2Threshold() != 800 || cfg.Windows[blindMac] != database.BlindChannel2 {
+		t.Fatalf("air quality settings %+v", cfg)
+	}
+	code, _ = sw.send("POST", "/v1.0/groups/1/airQuality", `{"enabled": true, "co2Threshold": 2000}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("invalid air quality update %v", code)
+	}
+	code, _ = sw.send("POST", "/v1.0/groups/3/airQuality", `{"enabled": true}`)
+	if code != http.StatusNotFound {
+		t.Errorf("unknown group update %v", code)
+	}
+	code, body = sw.get("/v1.0/groups/1/airQuality", true)
+	if code != http.StatusOK || !strings.Contains(body, `"co2Threshold": 800`) {
+		t.Errorf("air quality read %v %v", code, body)
+	}
+	code, _ = sw.send("DELETE", "/v1.0/groups/1/airQuality", "")
+	if code != http.StatusOK || database.GetGroupSettings(sw.db, database.AirQualitySettings, 1) != nil {
+		t.Errorf("air quality removal %v", code)
+	}
+	code, body = sw.get("/v1.0/groups/1/daylight", true)
+	if code != http.StatusOK || !strings.Contains(body, database.DaylightControllerStep) {
+		t.Errorf("default daylight settings %v %v", code, body)
+	}
+
+	//the group blind commands follow the cached blind channels
+	local := sw.localBroker()
+	local.Publish("/read/blind/"+blindMac+"/setup/hello", `{"mac": "`+blindMac+`"}`)
+	advanceUntil(t, sw.clock, time.Second, 10*time.Second, "group status", func() bool {
+		_, ok := sw.service.GetGroupsStatus()[1]
+		return ok
+	})
+	blindCommand := func() dl.BlindConf {
+		t.Helper()
+		blindTopic := "/write/blind/" + blindMac + "/update/settings"
+		sent := len(local.Sent(blindTopic))
+		code, body := sw.send("POST", "/v1.0/groups/1/commands", `{"blinds": 1}`)
+		if code != http.StatusOK {
+			t.Fatalf("group command %v %v", code, body)
+		}
+		eventually(t, "blind command", func() bool {
+			return len(local.Sent(blindTopic)) > sent
+		})
+		var conf dl.BlindConf
+		json.Unmarshal([]byte(local.Sent(blindTopic)[sent].Content), &conf)
+		return conf
+	}
+	server := sw.serverBroker()
+	server.Publish("/write/switch/"+switchMac+"/update/blindChannels", `{"blindChannels": {"1": {"channels": {"`+strings.ToLower(blindMac)+`": 1}}}}`)
+	if conf := blindCommand(); conf.Blind1 == nil || conf.Blind2 != nil {
+		t.Errorf("blind command on channel 1 %+v", conf)
+	}
+	server.Publish("/write/switch/"+switchMac+"/update/blindChannels", `{"blindChannels": {"1": {"channels": {"`+blindMac+`": 3}}}}`)
+	if conf := blindCommand(); conf.Blind1 == nil || conf.Blind2 != nil {
+		t.Errorf("invalid blind channels applied %+v", conf)
+	}
+	server.Publish("/remove/switch/"+switchMac+"/update/blindChannels", `{"blindChannels": {"1": {}}}`)
+	if conf := blindCommand(); conf.Blind1 == nil || conf.Blind2 == nil {
+		t.Errorf("blind command on both channels %+v", conf)
+	}
+}
+
+func TestServiceGroupLoop(t *testing.T) {
+	ruleBrightness := 400
+	rulePresence := 5
+	sw := newTestSwitch(t, func(db database.Database) {
+		database.UpdateGroupConfig(db, gm.GroupConfig{
+			Group:          1,
+			Leds:           []string{ledMac},
+			Sensors:        []string{sensorMac},
+			RuleBrightness: &ruleBrightness,
+			RulePresence:   &rulePresence,
+		})
+	})
+	local := sw.localBroker()
+	local.Publish("/read/led/"+ledMac+"/setup/hello", `{"mac": "`+ledMac+`"}`)
+
+	//the broker delivers the group events sent by the switch back to its own subscription
+	sensorStatus := func(presence bool, brightness int) {
+		t.Helper()
+		groupTopic := "/read/group/1/events/sensor"
+		sent := len(local.Sent(groupTopic))
+		status, _ := json.Marshal(map[string]interface{}{
+			"mac":           sensorMac,
+			"group":         1,
+			"presence":      presence,
+			"brightnessRaw": brightness,
+		})
+		local.Publish("/read/sensor/"+sensorMac+"/status/dump", string(status))
+		events := local.Sent(groupTopic)
+		if len(events) <= sent {
+			t.Fatal("no group sensor event")
+		}
+		local.Publish(groupTopic, events[len(events)-1].Content)
+	}
+	ledTopic := "/write/led/" + ledMac + "/update/settings"
+	ledSetpoint := func() int {
+		sent := local.Sent(ledTopic)
+		if len(sent) == 0 {
+			return -1
+		}
+		var conf dled.LedConf
+		json.Unmarshal([]byte(sent[len(sent)-1].Content), &conf)
+		if conf.SetpointAuto == nil {
+			return -1
+		}
+		return *conf.SetpointAuto
+	}
+	groupStatus := func() database.GroupStatus {
+		return sw.service.GetGroupsStatus()[1]
+	}
+
+	//someone comes in a dark room: the LEDs are raised at each correction interval
+	sensorStatus(true, 100)
+	advanceUntil(t, sw.clock, time.Second, 10*time.Second, "LEDs switched on", func() bool {
+		return ledSetpoint() > 0 && groupStatus().Presence
+	})
+	first := ledSetpoint()
+	advanceUntil(t, sw.clock, time.Second, 30*time.Second, "LEDs raised", func() bool {
+		return ledSetpoint() > first
+	})
+	if status := groupStatus(); status.Brightness != 100 {
+		t.Errorf("group brightness %v", status.Brightness)
+	}
+
+	//the room stays occupied during the presence rule delay after the last detection
+	sensorStatus(false, 100)
+	for i := 0; i < rulePresence-2; i++ {
+		sw.clock.Advance(time.Second)
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !groupStatus().Presence || ledSetpoint() <= 0 {
+		t.Fatalf("room empty before the presence delay: setpoint %v", ledSetpoint())
+	}
+	advanceUntil(t, sw.clock, time.Second, 30*time.Second, "empty room", func() bool {
+		return !groupStatus().Presence && ledSetpoint() == 0
+	})
+}
+
+func TestServiceSceneCheck(t *testing.T) {
+	sw := newTestSwitch(t, func(db database.Database) {
+		database.UpdateGroupConfig(db, gm.GroupConfig{
+			Group:  1,
+			Blinds: []string{blindMac},
+		})
+	})
+
+	for _, body := range []string{`{"setpointBlinds": 50}`, `{"setpointBlinds": -1}`, `{"setpointBlinds": 2, "setpointSlats": 200}`, `{"setpointLeds": 101}`} {
+		code, _ := sw.send("POST", "/v1.0/groups/1/scenes/night", body)
+		if code != http.StatusBadRequest {
+			t.Errorf("invalid scene %v accepted: %v", body, code)
+		}
+	}
+	if database.GetGroupScene(sw.db, 1, "night") != nil {
+		t.Fatal("invalid scene saved")
+	}
+	code, body := sw.send("POST", "/v1.0/groups/1/scenes/night", `{"setpointBlinds": 2, "setpointSlats": 45}`)
+	if code != http.StatusOK || database.GetGroupScene(sw.db, 1, "night") == nil {
+		t.Errorf("scene update %v %v", code, body)
+	}
+}
+
+func TestServiceEventsRevoked(t *testing.T) {
+	userHash := "user1"
+	sw := newTestSwitch(t, func(db database.Database) {
+		database.SaveUserConfig(db, duser.UserAccess{UserHash: userHash, AccessGroups: []int{1}})
+	})
+	code, body := sw.send("POST", "/v1.0/users/"+userHash+"/credential", `{"login": "operator", "password": "pass"}`)
+	if code != http.StatusOK {
+		t.Fatalf("user credential %v %v", code, body)
+	}
+	req, _ := http.NewRequest("POST", "/v1.0/user/login", strings.NewReader(`{"login": "operator", "password": "pass"}`))
+	rec := sw.server.Do(req)
+	var tok struct {
+		AccessToken string `json:"accessToken"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &tok)
+	if rec.Code != http.StatusOK || tok.AccessToken == "" {
+		t.Fatalf("user login %v %v", rec.Code, rec.Body.String())
+	}
+
+	srv := httptest.NewServer(sw.server.Handler())
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1.0/events?token="+tok.AccessToken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	//the event stream opened with the former rights is closed with the user tokens
+	code, body = sw.send("POST", "/v1.0/users/"+userHash+"/privilege", `{"privilege": "operator"}`)
+	if code != http.StatusOK {
+		t.Fatalf("user privilege %v %v", code, body)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if err == nil {
+		t.Fatal("event stream still open after the rights change")
+	}
+	if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
+		t.Fatal("event stream not closed after the rights change")
+	}
+}
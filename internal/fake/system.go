@@ -0,0 +1,74 @@
+package fake
+
+import (
+	"sync"
+)
+
+//System records the system actions instead of running them
+type System struct {
+	mutex    sync.Mutex
+	mac      string
+	ip       string
+	reboots  int
+	upgrades int
+	ips      []string
+}
+
+//NewSystem create a fake system with the given network addresses
+func NewSystem(mac, ip string) *System {
+	return &System{
+		mac: mac,
+		ip:  ip,
+	}
+}
+
+//NetworkInfo return the MAC and IP addresses given on creation
+func (sys *System) NetworkInfo() (string, string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	return sys.mac, sys.ip
+}
+
+//Reboot count the reboot requests
+func (sys *System) Reboot() error {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	sys.reboots++
+	return nil
+}
+
+//SetIPConfig record the requested IP configuration
+func (sys *System) SetIPConfig(ip string) error {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	sys.ips = append(sys.ips, ip)
+	return nil
+}
+
+//Upgrade count the system upgrade requests
+func (sys *System) Upgrade() {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	sys.upgrades++
+}
+
+//Reboots return the number of reboot requests
+func (sys *System) Reboots() int {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	return sys.reboots
+}
+
+//Upgrades return the number of system upgrade requests
+func (sys *System) Upgrades() int {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	return sys.upgrades
+}
+
+//IPConfigs return the requested IP configurations in order
+func (sys *System) IPConfigs() []string {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	return append([]string{}, sys.ips...)
+}
@@ -9,17 +9,35 @@ import (
 
 	"github.com/energieip/common-components-go/pkg/service"
 	"github.com/energieip/swh200-firmware-go/internal/core"
+	"github.com/energieip/swh200-firmware-go/internal/database"
 )
 
 func main() {
 	var confFile string
+	var rethinkDump string
 	var service service.IService
 
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	flag.StringVar(&confFile, "config", "", "Specify an alternate configuration file.")
 	flag.StringVar(&confFile, "c", "", "Specify an alternate configuration file.")
+	flag.StringVar(&rethinkDump, "import-rethinkdb", "", "Import a RethinkDB dump (archive or export folder) in the file storage and exit.")
 	flag.Parse()
 
+	if rethinkDump != "" {
+		storage, err := database.ReadStorageConfig(confFile)
+		if err != nil {
+			log.Println("Cannot read storage configuration " + err.Error())
+			os.Exit(1)
+		}
+		nb, err := database.MigrateRethinkDBDump(storage, rethinkDump)
+		if err != nil {
+			log.Println("Error during RethinkDB dump import " + err.Error())
+			os.Exit(1)
+		}
+		log.Printf("%v records imported in %v\n", nb, storage.Path)
+		os.Exit(0)
+	}
+
 	s := core.Service{}
 	service = &s
 	err := service.Initialize(confFile)